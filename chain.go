@@ -0,0 +1,314 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cihub/seelog"
+)
+
+// with chaining enabled each record in the log file ends with
+// " #<hex>" where the hex value is the SHA-256 of the previous
+// record's hash followed by the text of this record
+//
+// the chain continues across rotations and restarts, and the hash of
+// the last record is written to "<File>.chain" by Finalise
+const (
+	chainFormatter = "BitmarkChained"
+	chainSeparator = " #"
+	chainSuffix    = ".chain"
+
+	// format for the log file when chaining
+	chainFormat = "%" + chainFormatter + "%n"
+)
+
+type chainHash [sha256.Size]byte
+
+// current end of the chain
+type chain struct {
+	sync.Mutex
+	head chainHash
+}
+
+var chainState chain
+
+// compute the next link of the chain
+func (h chainHash) next(text string) chainHash {
+	return sha256.Sum256(append(h[:], text...))
+}
+
+func registerChainFormatter() {
+	_ = seelog.RegisterCustomFormatter(chainFormatter, func(param string) seelog.FormatterFunc {
+		return func(message string, l seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+			display, m, ok := splitMarker(message)
+			if !ok {
+				display = strings.ToUpper(l.String())
+			}
+			text := formatTime(messageTime(message, context)) + " [" + display + "] " + redact(m)
+
+			chainState.Lock()
+			chainState.head = chainState.head.next(text)
+			h := chainState.head
+			chainState.Unlock()
+
+			return text + chainSeparator + hex.EncodeToString(h[:])
+		}
+	})
+}
+
+// split a record into its text and hash
+func splitChained(record string) (string, chainHash, bool) {
+	var h chainHash
+	n := strings.LastIndex(record, chainSeparator)
+	if n < 0 {
+		return record, h, false
+	}
+	b, err := hex.DecodeString(record[n+len(chainSeparator):])
+	if nil != err || len(b) != len(h) {
+		return record, h, false
+	}
+	copy(h[:], b)
+	return record[:n], h, true
+}
+
+// resume the chain from the last record of an existing log file or,
+// if there is none, its latest archive
+func resumeChain(filepath string) error {
+	chainState.Lock()
+	defer chainState.Unlock()
+
+	chainState.head = chainHash{}
+
+	files, err := RotatedFiles(path.Split(filepath))
+	if nil != err || 0 == len(files) {
+		return err
+	}
+
+	f, err := openSegment(files[len(files)-1])
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 65536), 16*1024*1024)
+	for s.Scan() {
+		if _, h, ok := splitChained(s.Text()); ok {
+			chainState.head = h
+		}
+	}
+	return s.Err()
+}
+
+// record the end of the chain beside the log file
+func writeChainHead(filepath string) error {
+	chainState.Lock()
+	h := chainState.head
+	chainState.Unlock()
+
+	return os.WriteFile(filepath+chainSuffix, []byte(hex.EncodeToString(h[:])+"\n"), 0600)
+}
+
+// suffix of archives compressed after rotation, e.g. by logrotate
+const compressedSuffix = ".gz"
+
+// RotatedFiles - list a log file and its numbered archives, oldest
+// first, files that do not exist are omitted
+//
+// archives compressed after rotation, e.g. "app.log.3.gz", are listed
+// by their compressed names; an archive that exists both compressed
+// and uncompressed, i.e. while it is being compressed, is listed once
+// by its uncompressed name
+func RotatedFiles(directory string, file string) ([]string, error) {
+	entries, err := os.ReadDir(directory)
+	if nil != err {
+		return nil, err
+	}
+
+	archives := make(map[int]string, len(entries))
+	current := false
+	for _, e := range entries {
+		name := e.Name()
+		if name == file {
+			current = true
+			continue
+		}
+		n, ok := rotatedNumber(file, segmentName(name))
+		if !ok {
+			continue
+		}
+		if _, found := archives[n]; !found || name == segmentName(name) {
+			archives[n] = name
+		}
+	}
+	numbers := make([]int, 0, len(archives))
+	for n := range archives {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	files := make([]string, 0, len(archives)+1)
+	for _, n := range numbers {
+		files = append(files, path.Join(directory, archives[n]))
+	}
+	if current {
+		files = append(files, path.Join(directory, file))
+	}
+	return files, nil
+}
+
+// the archive number of a rotated log file name: "<file>.<n>"
+func rotatedNumber(file string, name string) (int, bool) {
+	if !strings.HasPrefix(name, file+".") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(name[len(file)+1:])
+	if nil != err {
+		return 0, false
+	}
+	return n, true
+}
+
+// the name of a log file or archive without any compression suffix
+func segmentName(name string) string {
+	return strings.TrimSuffix(name, compressedSuffix)
+}
+
+// a compressed archive, closing it closes the file too
+type compressedFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (c *compressedFile) Close() error {
+	c.Reader.Close()
+	return c.file.Close()
+}
+
+// open a log file or archive, compressed archives are decompressed
+func openSegment(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if nil != err || !strings.HasSuffix(name, compressedSuffix) {
+		return f, err
+	}
+	z, err := gzip.NewReader(f)
+	if nil != err {
+		f.Close()
+		return nil, err
+	}
+	return &compressedFile{Reader: z, file: f}, nil
+}
+
+// ChainError - the location of a broken link in a hash chained log
+type ChainError struct {
+	File   string
+	Line   int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	if 0 == e.Line {
+		return fmt.Sprintf("%s: %s", e.File, e.Reason)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Reason)
+}
+
+// VerifyChain - check the hash chain of a log file and its archives
+//
+// returns a *ChainError for the first broken link; the first record
+// found is trusted since earlier archives may have been deleted by
+// rotation.  If a chain head file exists then its hash must be present.
+func VerifyChain(directory string, file string) error {
+	files, err := RotatedFiles(directory, file)
+	if nil != err {
+		return err
+	}
+	if 0 == len(files) {
+		return &ChainError{File: path.Join(directory, file), Reason: "no log files"}
+	}
+
+	var head chainHash
+	haveHead := false
+	headFile := path.Join(directory, file+chainSuffix)
+	if b, err := os.ReadFile(headFile); nil == err {
+		h, err := hex.DecodeString(string(bytes.TrimSpace(b)))
+		if nil != err || len(h) != len(head) {
+			return &ChainError{File: headFile, Reason: "invalid chain head"}
+		}
+		copy(head[:], h)
+		haveHead = true
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	var previous chainHash
+	started := false
+	headFound := false
+
+	for _, name := range files {
+		f, err := openSegment(name)
+		if nil != err {
+			return err
+		}
+
+		s := bufio.NewScanner(f)
+		s.Buffer(make([]byte, 0, 65536), 16*1024*1024)
+		lineNumber := 0
+		pending := ""
+		for s.Scan() {
+			lineNumber += 1
+			pending += s.Text()
+
+			text, h, ok := splitChained(pending)
+			if !ok {
+				// part of a multi-line message
+				pending += "\n"
+				continue
+			}
+			pending = ""
+
+			if started && previous.next(text) != h {
+				f.Close()
+				return &ChainError{File: name, Line: lineNumber, Reason: "hash does not match previous record"}
+			}
+			previous = h
+			started = true
+			if haveHead && h == head {
+				headFound = true
+			}
+		}
+		err = s.Err()
+		f.Close()
+		if nil != err {
+			return err
+		}
+		if "" != pending {
+			return &ChainError{File: name, Line: lineNumber, Reason: "record without hash"}
+		}
+	}
+
+	if !started {
+		return &ChainError{File: path.Join(directory, file), Reason: "no chained records"}
+	}
+	if haveHead && !headFound {
+		return &ChainError{File: headFile, Reason: "chain head not found, log may be truncated"}
+	}
+
+	return nil
+}
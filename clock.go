@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// Clock - source of time for log timestamps
+type Clock func() time.Time
+
+// names for common timestamp layouts, any other value of
+// Configuration.Timestamp is used as a time.Format layout
+const (
+	TimestampDefault      = "default"      // 2006-01-02 15:04:05
+	TimestampRFC3339      = "rfc3339"      // 2006-01-02T15:04:05Z07:00
+	TimestampRFC3339Milli = "rfc3339milli" // 2006-01-02T15:04:05.000Z07:00
+	TimestampRFC3339Nano  = "rfc3339nano"  // 2006-01-02T15:04:05.999999999Z07:00
+	TimestampUnix         = "unix"         // seconds since the epoch
+	TimestampUnixMilli    = "unixmilli"    // milliseconds since the epoch
+)
+
+const (
+	defaultTimeLayout = "2006-01-02 15:04:05"
+	timeFormatter     = "BitmarkTime"
+
+	// with a clock each message carries its time in front between
+	// markers, so the clock is read once when the message is logged
+	// and every output formats that same time later
+	timeMarker = "\x01"
+)
+
+var timeLayouts = map[string]string{
+	"":                    defaultTimeLayout,
+	TimestampDefault:      defaultTimeLayout,
+	TimestampRFC3339:      time.RFC3339,
+	TimestampRFC3339Milli: "2006-01-02T15:04:05.000Z07:00",
+	TimestampRFC3339Nano:  time.RFC3339Nano,
+}
+
+// current timestamp settings, changed by Initialise and Finalise
+type timestamps struct {
+	sync.RWMutex
+	layout string
+	utc    bool
+}
+
+var timeSettings = timestamps{
+	layout: defaultTimeLayout,
+}
+
+// set the layout and zone of timestamps
+func setTimestamps(layout string, utc bool) {
+	if l, ok := timeLayouts[layout]; ok {
+		layout = l
+	}
+
+	timeSettings.Lock()
+	timeSettings.layout = layout
+	timeSettings.utc = utc
+	timeSettings.Unlock()
+}
+
+// check a timestamp setting: either one of the names or a layout that
+// can format a time and parse it back, so that a misspelt name, which
+// time.Format would copy into every record, is rejected
+func validateTimestamp(timestamp string) error {
+	if _, ok := timeLayouts[timestamp]; ok {
+		return nil
+	}
+	switch timestamp {
+	case TimestampUnix, TimestampUnixMilli:
+		return nil
+	}
+	reference := time.Date(2014, 8, 12, 22, 44, 35, 0, time.UTC)
+	t, err := time.Parse(timestamp, reference.Format(timestamp))
+	if nil != err || !t.Equal(reference) {
+		return &FieldError{Field: "Timestamp", Value: timestamp, Reason: "is not a timestamp name or layout"}
+	}
+	return nil
+}
+
+// the time of a message: the time stamped from a clock or, without a
+// clock, the actual call time
+func messageTime(message string, context seelog.LogContextInterface) time.Time {
+	if t, _, ok := splitTime(message); ok {
+		return t
+	}
+	return context.CallTime()
+}
+
+// separate the time stamped from a clock from the message
+func splitTime(message string) (time.Time, string, bool) {
+	if !strings.HasPrefix(message, timeMarker) {
+		return time.Time{}, message, false
+	}
+	n := strings.Index(message[len(timeMarker):], timeMarker)
+	if n < 0 {
+		return time.Time{}, message, false
+	}
+	start := len(timeMarker)
+	t, err := time.Parse(time.RFC3339Nano, message[start:start+n])
+	if nil != err {
+		return time.Time{}, message, false
+	}
+	return t, message[start+n+len(timeMarker):], true
+}
+
+// a seelog logger that stamps each message with the time from a clock
+type clockedLog struct {
+	seelog.LoggerInterface
+	clock Clock
+}
+
+// wrap a seelog logger to stamp messages, if there is a clock
+func withClock(log seelog.LoggerInterface, clock Clock) seelog.LoggerInterface {
+	if nil == clock {
+		return log
+	}
+	return &clockedLog{
+		LoggerInterface: log,
+		clock:           clock,
+	}
+}
+
+func (c *clockedLog) stamp(v []interface{}) string {
+	return timeMarker + c.clock().Format(time.RFC3339Nano) + timeMarker + fmt.Sprint(v...)
+}
+
+func (c *clockedLog) Trace(v ...interface{}) {
+	c.LoggerInterface.Trace(c.stamp(v))
+}
+
+func (c *clockedLog) Tracef(format string, params ...interface{}) {
+	c.Trace(fmt.Sprintf(format, params...))
+}
+
+func (c *clockedLog) Debug(v ...interface{}) {
+	c.LoggerInterface.Debug(c.stamp(v))
+}
+
+func (c *clockedLog) Debugf(format string, params ...interface{}) {
+	c.Debug(fmt.Sprintf(format, params...))
+}
+
+func (c *clockedLog) Info(v ...interface{}) {
+	c.LoggerInterface.Info(c.stamp(v))
+}
+
+func (c *clockedLog) Infof(format string, params ...interface{}) {
+	c.Info(fmt.Sprintf(format, params...))
+}
+
+func (c *clockedLog) Warn(v ...interface{}) error {
+	return c.LoggerInterface.Warn(c.stamp(v))
+}
+
+func (c *clockedLog) Warnf(format string, params ...interface{}) error {
+	return c.Warn(fmt.Sprintf(format, params...))
+}
+
+func (c *clockedLog) Error(v ...interface{}) error {
+	return c.LoggerInterface.Error(c.stamp(v))
+}
+
+func (c *clockedLog) Errorf(format string, params ...interface{}) error {
+	return c.Error(fmt.Sprintf(format, params...))
+}
+
+func (c *clockedLog) Critical(v ...interface{}) error {
+	return c.LoggerInterface.Critical(c.stamp(v))
+}
+
+func (c *clockedLog) Criticalf(format string, params ...interface{}) error {
+	return c.Critical(fmt.Sprintf(format, params...))
+}
+
+// render a timestamp for a message
+func formatTime(t time.Time) string {
+	timeSettings.RLock()
+	layout := timeSettings.layout
+	utc := timeSettings.utc
+	timeSettings.RUnlock()
+
+	if utc {
+		t = t.UTC()
+	}
+
+	switch layout {
+	case TimestampUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case TimestampUnixMilli:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	default:
+		return t.Format(layout)
+	}
+}
+
+func registerTimeFormatter() {
+	_ = seelog.RegisterCustomFormatter(timeFormatter, func(param string) seelog.FormatterFunc {
+		return func(message string, l seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+			return formatTime(messageTime(message, context))
+		}
+	})
+}
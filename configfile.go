@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configuration file formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// defaults applied by WithDefaults for omitted settings
+const (
+	DefaultSize  = 1048576      // bytes before the log file is rotated
+	DefaultCount = minimumCount // number of rotated files kept
+)
+
+// a document with a logging section
+type configurationSection struct {
+	Logging *Configuration `json:"logging" yaml:"logging" toml:"logging"`
+}
+
+// LoadConfiguration - read the logging configuration from a document
+// and apply the defaults
+//
+// the settings are taken from a top level "logging" section if there
+// is one, otherwise the whole document is the logging configuration
+// e.g. in YAML
+//
+//	logging:
+//	  directory: /var/lib/app/log
+//	  file: app.log
+//	  levels:
+//	    DEFAULT: info
+//	    p2p.*: debug
+//
+// or in TOML
+//
+//	[logging]
+//	directory = "/var/lib/app/log"
+//	file = "app.log"
+//	[logging.levels]
+//	DEFAULT = "info"
+//	"p2p.*" = "debug"
+func LoadConfiguration(data []byte, format string) (Configuration, error) {
+	var section configurationSection
+	var configuration Configuration
+
+	var err error
+	switch format {
+	case FormatJSON:
+		if err = json.Unmarshal(data, &section); nil == err && nil == section.Logging {
+			err = json.Unmarshal(data, &configuration)
+		}
+	case FormatYAML:
+		if err = yaml.Unmarshal(data, &section); nil == err && nil == section.Logging {
+			err = yaml.Unmarshal(data, &configuration)
+		}
+	case FormatTOML:
+		if _, err = toml.Decode(string(data), &section); nil == err && nil == section.Logging {
+			_, err = toml.Decode(string(data), &configuration)
+		}
+	default:
+		return configuration, fmt.Errorf("format: %q invalid", format)
+	}
+	if nil != err {
+		return configuration, err
+	}
+
+	if nil != section.Logging {
+		configuration = *section.Logging
+	}
+	return configuration.WithDefaults(), nil
+}
+
+// LoadConfigurationFile - read the logging configuration from a file,
+// the format is determined by the extension: .json, .yaml, .yml or .toml
+func LoadConfigurationFile(name string) (Configuration, error) {
+	format := strings.TrimPrefix(strings.ToLower(path.Ext(name)), ".")
+	if "yml" == format {
+		format = FormatYAML
+	}
+
+	data, err := os.ReadFile(name)
+	if nil != err {
+		return Configuration{}, err
+	}
+	return LoadConfiguration(data, format)
+}
+
+// WithDefaults - fill in the omitted settings that have defaults
+//
+//	size             DefaultSize
+//	count            DefaultCount
+//	levels           DEFAULT = DefaultLevel
+//	console_style    plain
+//	timestamp        default
+//	directory_mode   0750, if create_directory is set
+//	sampling_summary 1m0s, if sampling is set
+//
+// the directory and file have no defaults; maps are copied so the
+// original configuration is not modified
+func (configuration Configuration) WithDefaults() Configuration {
+	if 0 == configuration.Size {
+		configuration.Size = DefaultSize
+	}
+	if 0 == configuration.Count {
+		configuration.Count = DefaultCount
+	}
+
+	levels := make(map[string]string, len(configuration.Levels)+1)
+	for tag, l := range configuration.Levels {
+		levels[tag] = l
+	}
+	if _, ok := levels[DefaultTag]; !ok {
+		levels[DefaultTag] = DefaultLevel
+	}
+	configuration.Levels = levels
+
+	if "" == configuration.ConsoleStyle {
+		configuration.ConsoleStyle = ConsolePlain
+	}
+	if "" == configuration.Timestamp {
+		configuration.Timestamp = TimestampDefault
+	}
+	if configuration.CreateDirectory && "" == configuration.DirectoryMode {
+		configuration.DirectoryMode = "0750"
+	}
+	if 0 != len(configuration.Sampling) && "" == configuration.SamplingSummary {
+		configuration.SamplingSummary = defaultSamplingSummary.String()
+	}
+	return configuration
+}
+
+// Canonical - the configuration with defaults applied, as a logging
+// section in one of the formats
+//
+// map keys are sorted and settings that are not set are omitted from
+// YAML and TOML, so equivalent configurations produce the same output
+// and the output loads back to the same configuration
+func (configuration Configuration) Canonical(format string) ([]byte, error) {
+	configuration = configuration.WithDefaults()
+	section := configurationSection{
+		Logging: &configuration,
+	}
+
+	var buffer bytes.Buffer
+	switch format {
+	case FormatJSON:
+		e := json.NewEncoder(&buffer)
+		e.SetIndent("", "  ")
+		if err := e.Encode(section); nil != err {
+			return nil, err
+		}
+	case FormatYAML:
+		e := yaml.NewEncoder(&buffer)
+		e.SetIndent(2)
+		if err := e.Encode(section); nil != err {
+			return nil, err
+		}
+		e.Close()
+	case FormatTOML:
+		if err := toml.NewEncoder(&buffer).Encode(section); nil != err {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("format: %q invalid", format)
+	}
+	return buffer.Bytes(), nil
+}
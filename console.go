@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+// console styles
+const (
+	ConsolePlain  = "plain"  // same as the log file
+	ConsolePretty = "pretty" // colours, aligned tags and relative times
+)
+
+// pretty console output is one line per message:
+//
+//	+1.234s WARN     main     message text key=value
+//
+// the time is relative to Initialise; colours are omitted if NO_COLOR
+// is set to a non-empty value and plain output is used if stdout is
+// not a terminal
+const (
+	consoleFormatter = "BitmarkConsole"
+	consoleFormat    = "%" + consoleFormatter + "%n"
+
+	ansiReset = "\x1b[0m"
+	ansiFaint = "\x1b[2m"
+
+	minimumTagWidth = 8
+)
+
+// state for pretty console output
+type console struct {
+	sync.Mutex
+	start    time.Time
+	colour   bool
+	tagWidth int
+}
+
+var consoleState = console{
+	tagWidth: minimumTagWidth,
+}
+
+// replaceable so that tests can simulate a terminal
+var stdoutIsTerminal = func() bool {
+	return isTerminal(os.Stdout)
+}
+
+// decide the console format for a style
+func consoleFormatID(style string) (string, error) {
+	if err := validateConsoleStyle(style); nil != err {
+		return "", err
+	}
+	if ConsolePretty != style || !stdoutIsTerminal() {
+		return "all", nil
+	}
+
+	// see https://no-color.org: an empty value does not disable colour
+	noColour := "" != os.Getenv("NO_COLOR")
+
+	consoleState.Lock()
+	consoleState.start = time.Now()
+	consoleState.colour = !noColour
+	consoleState.tagWidth = minimumTagWidth
+	consoleState.Unlock()
+
+	return "console", nil
+}
+
+// check a console style
+func validateConsoleStyle(style string) error {
+	switch style {
+	case "", ConsolePlain, ConsolePretty:
+		return nil
+	default:
+		return &FieldError{Field: "ConsoleStyle", Value: style, Reason: "is not a console style"}
+	}
+}
+
+// check if a file is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if nil != err {
+		return false
+	}
+	return 0 != info.Mode()&os.ModeCharDevice
+}
+
+func registerConsoleFormatter() {
+	_ = seelog.RegisterCustomFormatter(consoleFormatter, func(param string) seelog.FormatterFunc {
+		return func(message string, l seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+			return prettyMessage(message, l, messageTime(message, context))
+		}
+	})
+}
+
+// render a message for the console
+func prettyMessage(message string, l seelog.LogLevel, t time.Time) string {
+	d, ok := level.Lookup(int(recordLevel(message, l)))
+	if !ok {
+		d.Display = strings.ToUpper(l.String())
+	}
+	_, message, _ = splitMarker(message)
+	message = redact(message)
+
+	tag := ""
+	if n := strings.Index(message, tagSuffix); n >= 0 {
+		tag = message[:n]
+		message = message[n+len(tagSuffix):]
+	}
+
+	consoleState.Lock()
+	elapsed := t.Sub(consoleState.start)
+	colour := consoleState.colour
+	if len(tag) > consoleState.tagWidth {
+		consoleState.tagWidth = len(tag)
+	}
+	width := consoleState.tagWidth
+	consoleState.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%+9.3fs ", elapsed.Seconds())
+	if colour && "" != d.Colour {
+		fmt.Fprintf(&b, "\x1b[%sm%-8s%s ", d.Colour, d.Display, ansiReset)
+	} else {
+		fmt.Fprintf(&b, "%-8s ", d.Display)
+	}
+	fmt.Fprintf(&b, "%-*s ", width, tag)
+
+	if !colour {
+		b.WriteString(message)
+		return b.String()
+	}
+
+	// faint keys for key=value items
+	i := 0
+	for _, f := range scanFields(message) {
+		b.WriteString(message[i:f.start])
+		b.WriteString(ansiFaint)
+		b.WriteString(message[f.start : f.equals+1])
+		b.WriteString(ansiReset)
+		b.WriteString(message[f.equals+1 : f.end])
+		i = f.end
+	}
+	b.WriteString(message[i:])
+
+	return b.String()
+}
@@ -8,4 +8,9 @@
 // Implements multiple log channels to a single rotated log file.  Log
 // levels are considered a simple hierarchy with each channel having a
 // single limit, below which logs to that channel are skipped.
+//
+// Tags may be hierarchical, separated by dots, e.g. "p2p.peer" so that
+// a level configured for "p2p" or "p2p.*" applies to all descendants.
 package logger
+
+//go:generate go run ./makeloggerinterface -output interface.go
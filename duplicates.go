@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// collapses runs of identical messages on a tag into the first message
+// followed by a summary of the number of repeats, the summary is written
+// when a different message arrives or when the window expires
+type deduplicator struct {
+	sync.Mutex
+	log         seelog.LoggerInterface // where summaries are written
+	window      time.Duration
+	levelNumber int
+	message     string
+	start       time.Time
+	repeats     int
+	timer       *time.Timer // ends the current run at the end of the window, reset for each run
+}
+
+func (d *deduplicator) allow(levelNumber int, message string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	now := time.Now()
+	if levelNumber == d.levelNumber && message == d.message && now.Sub(d.start) < d.window {
+		d.repeats += 1
+		return false
+	}
+
+	d.summarise()
+	d.levelNumber = levelNumber
+	d.message = message
+	d.start = now
+	if nil == d.timer {
+		d.timer = time.AfterFunc(d.window, d.expire)
+	} else {
+		d.timer.Reset(d.window)
+	}
+	return true
+}
+
+// the window of a run may have passed
+func (d *deduplicator) expire() {
+	d.Lock()
+	defer d.Unlock()
+
+	// a timer that fires as its run ends must not end the next one,
+	// which was started within its window and reset the timer
+	if 0 != d.levelNumber && time.Since(d.start) >= d.window {
+		d.summarise()
+	}
+}
+
+// end the current run, logging the number of repeats if any
+// must be called with the deduplicator locked
+func (d *deduplicator) summarise() {
+	if nil != d.timer {
+		d.timer.Stop()
+	}
+	if d.repeats > 0 {
+		outputAt(d.log, d.levelNumber, repeatedMessage(d.message, d.repeats))
+	}
+	d.levelNumber = 0
+	d.message = ""
+	d.repeats = 0
+}
+
+// the summary for a run keeping the tag and any custom level of the
+// original message
+func repeatedMessage(message string, repeats int) string {
+	marker := ""
+	if display, m, ok := splitMarker(message); ok {
+		marker = levelMarker + display + levelMarker
+		message = m
+	}
+	tag := ""
+	if n := strings.Index(message, tagSuffix); n >= 0 {
+		tag = message[:n+len(tagSuffix)]
+	}
+	return marker + tag + fmt.Sprintf("last message repeated %d times", repeats)
+}
+
+// check the deduplication window
+func validateDeduplicate(window string) (time.Duration, error) {
+	if "" == window {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(window)
+	if nil != err || d <= 0 {
+		return 0, &FieldError{Field: "Deduplicate", Value: window, Reason: "is not a positive duration"}
+	}
+	return d, nil
+}
+
+// the deduplicator for a tag, if enabled
+// globalData must be locked by caller
+func deduplicatorForTag(tag string) *deduplicator {
+	if globalData.dedupWindow <= 0 {
+		return nil
+	}
+	if d, ok := globalData.dedupers[tag]; ok {
+		return d
+	}
+	d := &deduplicator{
+		log:    seelog.Current,
+		window: globalData.dedupWindow,
+	}
+	if nil == globalData.dedupers {
+		globalData.dedupers = make(map[string]*deduplicator)
+	}
+	globalData.dedupers[tag] = d
+	return d
+}
+
+// end all runs of duplicates
+// globalData must be locked by caller
+func flushDuplicates() {
+	tags := make([]string, 0, len(globalData.dedupers))
+	for tag := range globalData.dedupers {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		d := globalData.dedupers[tag]
+		d.Lock()
+		d.summarise()
+		d.Unlock()
+	}
+}
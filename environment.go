@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithEnvironment - apply environment variables over a configuration
+//
+// each setting is named by the prefix and its json tag in upper case,
+// e.g. with prefix "APP_LOG":
+//
+//	APP_LOG_DIRECTORY=/var/lib/app/log
+//	APP_LOG_SIZE=1048576
+//	APP_LOG_CONSOLE=true
+//	APP_LOG_LEVELS=main=debug,peer=warn
+//	APP_LOG_SAMPLING={"peer":{"type":"random","probability":0.1}}
+//
+// precedence is: environment variables, then the configuration given
+// (usually read from a file), then the defaults.  Maps are merged by
+// key, so APP_LOG_LEVELS only changes the tags it names; any setting
+// may be given as JSON, which is merged over the existing value in the
+// same way as a configuration file.  The configuration given is not
+// modified.
+//
+// returns ValidationErrors naming the variables that could not be
+// parsed
+func (configuration Configuration) WithEnvironment(prefix string) (Configuration, error) {
+	prefix = strings.TrimSuffix(prefix, "_") + "_"
+
+	errs := ValidationErrors{}
+	v := reflect.ValueOf(&configuration).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i += 1 {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if "" == name || "-" == name {
+			continue
+		}
+		variable := prefix + strings.ToUpper(name)
+		s, ok := os.LookupEnv(variable)
+		if !ok {
+			continue
+		}
+		errs.add(setFromEnvironment(variable, s, v.Field(i)))
+	}
+	return configuration, errs.err()
+}
+
+// set a field from the value of an environment variable
+func setFromEnvironment(variable string, s string, field reflect.Value) error {
+	if strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") {
+		return mergeJSON(variable, s, field)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if nil != err {
+			return &FieldError{Field: variable, Value: s, Reason: "is not a number"}
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if nil != err {
+			return &FieldError{Field: variable, Value: s, Reason: "is not a boolean"}
+		}
+		field.SetBool(b)
+	case reflect.Map:
+		if field.Type().Elem().Kind() != reflect.String {
+			return &FieldError{Field: variable, Value: s, Reason: "must be JSON"}
+		}
+		m := make(map[string]string)
+		for k, v := range field.Interface().(map[string]string) {
+			m[k] = v
+		}
+		for _, item := range strings.Split(s, ",") {
+			if "" == strings.TrimSpace(item) {
+				continue
+			}
+			k, v, ok := strings.Cut(item, "=")
+			if !ok || "" == strings.TrimSpace(k) {
+				return &FieldError{Field: variable, Value: s, Reason: "is not a list of key=value"}
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		field.Set(reflect.ValueOf(m))
+	default:
+		return &FieldError{Field: variable, Value: s, Reason: "must be JSON"}
+	}
+	return nil
+}
+
+// merge a JSON value over a field, the field is copied first so that
+// maps and slices shared with the original configuration are not
+// modified
+func mergeJSON(variable string, s string, field reflect.Value) error {
+	current, err := json.Marshal(field.Interface())
+	if nil != err {
+		return &FieldError{Field: variable, Value: s, Reason: err.Error()}
+	}
+	merged := reflect.New(field.Type())
+	if err := json.Unmarshal(current, merged.Interface()); nil != err {
+		return &FieldError{Field: variable, Value: s, Reason: err.Error()}
+	}
+	if err := json.Unmarshal([]byte(s), merged.Interface()); nil != err {
+		return &FieldError{Field: variable, Value: s, Reason: "is not valid JSON: " + err.Error()}
+	}
+	field.Set(merged.Elem())
+	return nil
+}
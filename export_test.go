@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// render a warning for the pretty console 1.5s after start
+func PrettyWarning(message string, colour bool) string {
+	start := time.Date(2014, 8, 12, 10, 44, 35, 0, time.UTC)
+
+	consoleState.Lock()
+	consoleState.start = start
+	consoleState.colour = colour
+	consoleState.tagWidth = minimumTagWidth
+	consoleState.Unlock()
+
+	return prettyMessage(message, seelog.WarnLvl, start.Add(1500*time.Millisecond))
+}
+
+// the console format for a style as if stdout was or was not a
+// terminal, and whether colour would be used
+func ConsoleFormat(style string, terminal bool) (string, bool, error) {
+	saved := stdoutIsTerminal
+	stdoutIsTerminal = func() bool { return terminal }
+	defer func() { stdoutIsTerminal = saved }()
+
+	id, err := consoleFormatID(style)
+
+	consoleState.Lock()
+	defer consoleState.Unlock()
+	return id, consoleState.colour, err
+}
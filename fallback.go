@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cihub/seelog"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+// Fallback - what to do with a message sent to a nil channel or to
+// a channel that was not obtained from New
+type Fallback int32
+
+// possible fallbacks
+const (
+	FallbackPanic  Fallback = iota // panic with "logger is not initialised" (default)
+	FallbackDrop                   // silently discard the message
+	FallbackStderr                 // write the message to standard error
+)
+
+// Discard - a channel that discards all messages, safe to use at any
+// time without calling Initialise
+var Discard = &L{
+	discard: true,
+}
+
+// the current fallback
+var fallback atomic.Int32
+
+// SetFallback - select the behaviour for messages to nil channels or
+// to zero value channels, e.g. a field that was never set from New
+//
+// daemons should keep the default FallbackPanic so that a missing
+// Initialise is found immediately, libraries used by tools that never
+// configure logging can choose FallbackDrop or FallbackStderr
+//
+// the fallback does not apply to channels from New: before Initialise
+// and after Finalise they write to standard output whatever the
+// fallback, so a tool that must stay silent should use Discard in
+// place of its channels
+func SetFallback(f Fallback) {
+	fallback.Store(int32(f))
+}
+
+// the channel and binding to use for a message, a nil binding if the
+// message is to be dropped
+//
+// a channel from New is always usable, before Initialise and after
+// Finalise it writes to standard output
+func usableLogger(l *L) (*L, *binding) {
+	if nil != l && l.discard {
+		return nil, nil
+	}
+	if nil != l {
+		if b := l.current(); nil != b {
+			return l, b
+		}
+	}
+
+	switch Fallback(fallback.Load()) {
+	case FallbackDrop:
+		return nil, nil
+	case FallbackStderr:
+		return stderrChannel(l)
+	default:
+		panic("logger is not initialised")
+	}
+}
+
+// shared seelog logger for standard error
+var stderrLog struct {
+	sync.Once
+	log seelog.LoggerInterface
+}
+
+// a binding like that of l that writes to standard error, a nil or
+// unbound channel uses the default level
+func stderrChannel(l *L) (*L, *binding) {
+	stderrLog.Do(func() {
+		log, err := seelog.LoggerFromWriterWithMinLevelAndFormat(os.Stderr, seelog.TraceLvl, logFormat)
+		if nil != err {
+			log = seelog.Disabled
+		}
+		stderrLog.log = log
+	})
+
+	b := &binding{
+		log:   stderrLog.log,
+		level: DefaultLevel,
+	}
+	b.levelNumber, _ = level.Number(DefaultLevel)
+	if nil == l {
+		return &L{detached: true}, b
+	}
+	if current := l.binding.Load(); nil != current {
+		b.level = current.level
+		b.levelNumber = current.levelNumber
+	}
+	return l, b
+}
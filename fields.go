@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"strconv"
+	"strings"
+)
+
+// structured values are written into messages as key=value items,
+// with values quoted by %q when they contain spaces
+// e.g.
+//   log.Infof("connected peer=%s count=%d note=%q", address, n, note)
+
+// a key=value item within a message
+type field struct {
+	start  int // index of the key
+	equals int // index of the '='
+	end    int // index after the value
+	key    string
+	value  string
+}
+
+// locate the key=value items of a message
+func scanFields(message string) []field {
+	fields := make([]field, 0, 4)
+
+	for i := 0; i < len(message); {
+		if ' ' == message[i] {
+			i += 1
+			continue
+		}
+		n := strings.IndexAny(message[i:], "= ")
+		if n <= 0 || '=' != message[i+n] {
+			// not an item, skip the word
+			if e := strings.IndexByte(message[i:], ' '); e >= 0 {
+				i += e
+			} else {
+				i = len(message)
+			}
+			continue
+		}
+
+		f := field{
+			start:  i,
+			equals: i + n,
+			key:    message[i : i+n],
+		}
+		v := f.equals + 1
+		rest := message[v:]
+		if strings.HasPrefix(rest, `"`) {
+			if q, err := strconv.QuotedPrefix(rest); nil == err {
+				f.value, _ = strconv.Unquote(q)
+				f.end = v + len(q)
+			} else {
+				f.value = rest
+				f.end = len(message)
+			}
+		} else if e := strings.IndexByte(rest, ' '); e >= 0 {
+			f.value = rest[:e]
+			f.end = v + e
+		} else {
+			f.value = rest
+			f.end = len(message)
+		}
+		fields = append(fields, f)
+		i = f.end
+	}
+
+	return fields
+}
+
+// Fields - extract the key=value items from a message
+func Fields(message string) map[string]string {
+	result := make(map[string]string)
+	for _, f := range scanFields(message) {
+		result[f.key] = f.value
+	}
+	return result
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+
+	"github.com/cihub/seelog"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+// a filter decides if a message of a channel is to be output
+type filter interface {
+	allow(levelNumber int, message string) bool
+}
+
+// a seelog logger that passes each message through filters first
+type filteredLog struct {
+	seelog.LoggerInterface
+	filters []filter
+}
+
+// wrap a seelog logger with filters, if there are any
+func withFilters(log seelog.LoggerInterface, filters ...filter) seelog.LoggerInterface {
+	if 0 == len(filters) {
+		return log
+	}
+	return &filteredLog{
+		LoggerInterface: log,
+		filters:         filters,
+	}
+}
+
+func (f *filteredLog) allow(levelNumber int, message string) bool {
+	for _, item := range f.filters {
+		if !item.allow(levelNumber, message) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *filteredLog) Trace(v ...interface{}) {
+	if s := fmt.Sprint(v...); f.allow(level.TraceLevel, s) {
+		f.LoggerInterface.Trace(s)
+	}
+}
+
+func (f *filteredLog) Tracef(format string, params ...interface{}) {
+	f.Trace(fmt.Sprintf(format, params...))
+}
+
+func (f *filteredLog) Debug(v ...interface{}) {
+	if s := fmt.Sprint(v...); f.allow(level.DebugLevel, s) {
+		f.LoggerInterface.Debug(s)
+	}
+}
+
+func (f *filteredLog) Debugf(format string, params ...interface{}) {
+	f.Debug(fmt.Sprintf(format, params...))
+}
+
+func (f *filteredLog) Info(v ...interface{}) {
+	if s := fmt.Sprint(v...); f.allow(level.InfoLevel, s) {
+		f.LoggerInterface.Info(s)
+	}
+}
+
+func (f *filteredLog) Infof(format string, params ...interface{}) {
+	f.Info(fmt.Sprintf(format, params...))
+}
+
+func (f *filteredLog) Warn(v ...interface{}) error {
+	if s := fmt.Sprint(v...); f.allow(level.WarnLevel, s) {
+		return f.LoggerInterface.Warn(s)
+	}
+	return nil
+}
+
+func (f *filteredLog) Warnf(format string, params ...interface{}) error {
+	return f.Warn(fmt.Sprintf(format, params...))
+}
+
+func (f *filteredLog) Error(v ...interface{}) error {
+	if s := fmt.Sprint(v...); f.allow(level.ErrorLevel, s) {
+		return f.LoggerInterface.Error(s)
+	}
+	return nil
+}
+
+func (f *filteredLog) Errorf(format string, params ...interface{}) error {
+	return f.Error(fmt.Sprintf(format, params...))
+}
+
+func (f *filteredLog) Critical(v ...interface{}) error {
+	if s := fmt.Sprint(v...); f.allow(level.CriticalLevel, s) {
+		return f.LoggerInterface.Critical(s)
+	}
+	return nil
+}
+
+func (f *filteredLog) Criticalf(format string, params ...interface{}) error {
+	return f.Critical(fmt.Sprintf(format, params...))
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package level
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Definition - description of a named level
+//
+// example of adding levels (should be done before logging starts,
+// e.g. from an init function)
+//
+//	level.Register(level.Definition{
+//	  Name:   "notice",
+//	  Number: level.InfoLevel + 5,
+//	  Colour: "36",
+//	})
+//	level.Register(level.Definition{
+//	  Name:       "audit",
+//	  Number:     level.CriticalLevel + 5,
+//	  Unfiltered: true,
+//	})
+type Definition struct {
+	Name       string // lower case name used in configuration
+	Number     int    // ordering relative to the standard levels
+	Display    string // shown in log output, defaults to upper case Name
+	Colour     string // ANSI SGR colour code for console output e.g. "36"
+	Unfiltered bool   // always output regardless of channel level
+}
+
+// Standard returns true if the definition is one of the built in levels
+func (d Definition) Standard() bool {
+	return d.Number%Spacing == 0
+}
+
+// Base - the standard level at or below this level, used to select
+// the seelog call for the output
+func (d Definition) Base() int {
+	n := d.Number - d.Number%Spacing
+	if n < TraceLevel {
+		n = TraceLevel
+	}
+	return n
+}
+
+var (
+	definitionsLock sync.RWMutex
+	definitions     = map[int]Definition{
+		TraceLevel:    {Name: Trace, Number: TraceLevel, Display: "TRACE", Colour: "37"},
+		DebugLevel:    {Name: Debug, Number: DebugLevel, Display: "DEBUG", Colour: "34"},
+		InfoLevel:     {Name: Info, Number: InfoLevel, Display: "INFO", Colour: "32"},
+		WarnLevel:     {Name: Warn, Number: WarnLevel, Display: "WARN", Colour: "33"},
+		ErrorLevel:    {Name: Error, Number: ErrorLevel, Display: "ERROR", Colour: "31"},
+		CriticalLevel: {Name: Critical, Number: CriticalLevel, Display: "CRITICAL", Colour: "35"},
+		OffLevel:      {Name: Off, Number: OffLevel, Display: "OFF"},
+	}
+)
+
+// Register - add a custom level
+//
+// the number must be between TraceLevel and OffLevel and must not
+// coincide with a standard level; the name is accepted anywhere a
+// level name is parsed
+func Register(d Definition) error {
+	if "" == d.Name || d.Name != strings.ToLower(d.Name) {
+		return fmt.Errorf("level name: %q must be non-empty lower case", d.Name)
+	}
+	if d.Number <= TraceLevel || d.Number >= OffLevel || 0 == d.Number%Spacing {
+		return fmt.Errorf("level number: %d invalid for: %q", d.Number, d.Name)
+	}
+	if "" == d.Display {
+		d.Display = strings.ToUpper(d.Name)
+	}
+
+	definitionsLock.Lock()
+	defer definitionsLock.Unlock()
+
+	if _, ok := ValidLevels[d.Name]; ok {
+		return fmt.Errorf("level name: %q already exists", d.Name)
+	}
+	if _, ok := definitions[d.Number]; ok {
+		return fmt.Errorf("level number: %d already exists", d.Number)
+	}
+
+	definitions[d.Number] = d
+	ValidLevels[d.Name] = d.Number
+
+	return nil
+}
+
+// Lookup - find the definition of a level number
+func Lookup(number int) (Definition, bool) {
+	definitionsLock.RLock()
+	defer definitionsLock.RUnlock()
+
+	d, ok := definitions[number]
+	return d, ok
+}
+
+// Number - find the number of a level name, safe to call while levels
+// are being registered
+func Number(name string) (int, bool) {
+	definitionsLock.RLock()
+	defer definitionsLock.RUnlock()
+
+	n, ok := ValidLevels[name]
+	return n, ok
+}
+
+// Definitions - all levels in ascending order
+func Definitions() []Definition {
+	definitionsLock.RLock()
+	defer definitionsLock.RUnlock()
+
+	result := make([]Definition, 0, len(definitions))
+	for n := TraceLevel; n <= OffLevel; n += 1 {
+		if d, ok := definitions[n]; ok {
+			result = append(result, d)
+		}
+	}
+	return result
+}
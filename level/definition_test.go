@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package level_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+func TestRegister(t *testing.T) {
+	err := level.Register(level.Definition{
+		Name:   "verbose",
+		Number: level.DebugLevel + 5,
+		Colour: "36",
+	})
+	assert.Nil(t, err, "wrong Register")
+
+	assert.Equal(t, level.DebugLevel+5, level.ValidLevels["verbose"], "wrong verbose number")
+	n, ok := level.Number("verbose")
+	assert.True(t, ok, "verbose number not found")
+	assert.Equal(t, level.DebugLevel+5, n, "wrong verbose number")
+
+	d, ok := level.Lookup(level.DebugLevel + 5)
+	assert.True(t, ok, "verbose not found")
+	assert.Equal(t, "VERBOSE", d.Display, "wrong default display")
+	assert.False(t, d.Standard(), "verbose is not standard")
+	assert.Equal(t, level.DebugLevel, d.Base(), "wrong base level")
+
+	ds := level.Definitions()
+	for i := 1; i < len(ds); i += 1 {
+		assert.Less(t, ds[i-1].Number, ds[i].Number, "definitions not in order")
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	invalid := []level.Definition{
+		{Name: "", Number: level.InfoLevel + 1},
+		{Name: "Upper", Number: level.InfoLevel + 1},
+		{Name: "standard", Number: level.InfoLevel},
+		{Name: "low", Number: level.TraceLevel - 1},
+		{Name: "high", Number: level.OffLevel},
+		{Name: level.Warn, Number: level.WarnLevel + 1},
+	}
+	for _, d := range invalid {
+		assert.NotNil(t, level.Register(d), "registered invalid: %+v", d)
+	}
+
+	err := level.Register(level.Definition{Name: "first", Number: level.ErrorLevel + 3})
+	assert.Nil(t, err, "wrong Register")
+	err = level.Register(level.Definition{Name: "second", Number: level.ErrorLevel + 3})
+	assert.NotNil(t, err, "duplicate number registered")
+}
+
+func TestRegisterConcurrent(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i < level.Spacing; i += 1 {
+			level.Register(level.Definition{
+				Name:   "concurrent" + strconv.Itoa(i),
+				Number: level.TraceLevel + i,
+			})
+		}
+	}()
+
+	// lookups while levels are being registered
+	for i := 0; i < 100; i += 1 {
+		n, ok := level.Number(level.Info)
+		assert.True(t, ok, "info not found")
+		assert.Equal(t, level.InfoLevel, n, "wrong info number")
+		level.Parse("concurrent1") // may not be registered yet
+	}
+	<-done
+
+	n, ok := level.Number("concurrent9")
+	assert.True(t, ok, "registered level not found")
+	assert.Equal(t, level.TraceLevel+9, n, "wrong registered number")
+}
@@ -5,9 +5,16 @@
 
 package level
 
+// spacing between the standard levels to leave room for custom levels
+const Spacing = 10
+
 // simple ordering to allow <= to decide if log will be outputTarget
+//
+// the numbers were 1 to 7 before custom levels were added, so any
+// stored or compared numeric values must be multiplied by Spacing;
+// level names are unchanged
 const (
-	_ = iota
+	_ = iota * Spacing
 	TraceLevel
 	DebugLevel
 	InfoLevel
@@ -27,7 +34,11 @@ const (
 	Off      = "off"
 )
 
-// This needs to correspond to seelog levels
+// This needs to correspond to seelog levels, plus any custom levels
+// added by Register
+//
+// only read the map directly once registration is complete, otherwise
+// use Number; it must only be changed through Register
 var ValidLevels = map[string]int{
 	Trace:    TraceLevel,
 	Debug:    DebugLevel,
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package level
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level - a level number that can be used directly in configuration
+// structures and as a command-line flag
+//
+// example of use
+//
+//	type Config struct {
+//	  Verbosity level.Level `json:"verbosity"`
+//	}
+//
+//	var verbosity = level.Level(level.InfoLevel)
+//	flag.Var(&verbosity, "level", "log level")
+type Level int
+
+// alternative names accepted by Parse
+var aliases = map[string]string{
+	"warning": Warn,
+	"err":     Error,
+	"crit":    Critical,
+	"fatal":   Critical,
+	"none":    Off,
+}
+
+// Parse - convert a level name to a Level
+//
+// names are case-insensitive and include custom levels and some common
+// aliases e.g. "WARNING", "fatal", "err"
+func Parse(s string) (Level, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+
+	n, ok := Number(name)
+	if !ok {
+		return 0, fmt.Errorf("level %s invalid", s)
+	}
+	return Level(n), nil
+}
+
+// String - the name of the level
+func (l Level) String() string {
+	if d, ok := Lookup(int(l)); ok {
+		return d.Name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// MarshalText - convert to the level name, or to an empty string for
+// the zero Level so that an unset level can be written and read back
+func (l Level) MarshalText() ([]byte, error) {
+	if 0 == l {
+		return []byte{}, nil
+	}
+	if _, ok := Lookup(int(l)); !ok {
+		return nil, fmt.Errorf("level %d invalid", int(l))
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText - convert from a level name, an empty string gives the
+// zero Level
+func (l *Level) UnmarshalText(text []byte) error {
+	if 0 == len(text) {
+		*l = 0
+		return nil
+	}
+	n, err := Parse(string(text))
+	if nil != err {
+		return err
+	}
+	*l = n
+	return nil
+}
+
+// Set - implements flag.Value
+func (l *Level) Set(s string) error {
+	n, err := Parse(s)
+	if nil != err {
+		return err
+	}
+	*l = n
+	return nil
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package level_test
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]int{
+		"trace":    level.TraceLevel,
+		"DEBUG":    level.DebugLevel,
+		" Info ":   level.InfoLevel,
+		"warning":  level.WarnLevel,
+		"Err":      level.ErrorLevel,
+		"fatal":    level.CriticalLevel,
+		"critical": level.CriticalLevel,
+		"off":      level.OffLevel,
+	}
+	for s, expected := range tests {
+		l, err := level.Parse(s)
+		assert.Nil(t, err, "wrong Parse: %q", s)
+		assert.Equal(t, level.Level(expected), l, "wrong level: %q", s)
+	}
+
+	_, err := level.Parse("loud")
+	assert.NotNil(t, err, "invalid level parsed")
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "warn", level.Level(level.WarnLevel).String(), "wrong warn")
+	assert.Equal(t, "level(3)", level.Level(3).String(), "wrong unknown")
+}
+
+func TestJSON(t *testing.T) {
+	type config struct {
+		Level level.Level `json:"level"`
+	}
+
+	var c config
+	err := json.Unmarshal([]byte(`{"level":"WARNING"}`), &c)
+	assert.Nil(t, err, "wrong unmarshal")
+	assert.Equal(t, level.Level(level.WarnLevel), c.Level, "wrong level")
+
+	bs, err := json.Marshal(c)
+	assert.Nil(t, err, "wrong marshal")
+	assert.Equal(t, `{"level":"warn"}`, string(bs), "wrong json")
+
+	err = json.Unmarshal([]byte(`{"level":"loud"}`), &c)
+	assert.NotNil(t, err, "invalid level unmarshalled")
+
+	_, err = json.Marshal(config{Level: 3})
+	assert.NotNil(t, err, "invalid level marshalled")
+
+	// an unset level survives a round trip
+	bs, err = json.Marshal(config{})
+	assert.Nil(t, err, "wrong marshal of zero level")
+	assert.Equal(t, `{"level":""}`, string(bs), "wrong json")
+	c = config{Level: level.InfoLevel}
+	err = json.Unmarshal(bs, &c)
+	assert.Nil(t, err, "wrong unmarshal of empty level")
+	assert.Equal(t, level.Level(0), c.Level, "wrong zero level")
+}
+
+func TestFlag(t *testing.T) {
+	l := level.Level(level.InfoLevel)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&l, "level", "log level")
+	err := fs.Parse([]string{"-level", "debug"})
+	assert.Nil(t, err, "wrong flag parse")
+	assert.Equal(t, level.Level(level.DebugLevel), l, "wrong level")
+
+	err = fs.Parse([]string{"-level", ""})
+	assert.NotNil(t, err, "empty flag accepted")
+}
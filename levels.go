@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cihub/seelog"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+// custom levels are written through seelog at their base level with
+// the display name carried in front of the message between markers,
+// the custom formatters below extract it for the [%LEVEL] field
+const (
+	levelMarker = "\x00"
+
+	levelFormatter   = "BitmarkLevel"
+	messageFormatter = "BitmarkMsg"
+
+	// format for all outputs
+	logFormat = "%" + timeFormatter + " [%" + levelFormatter + "] %" + messageFormatter + "%n"
+)
+
+func registerFormatters() {
+	registerTimeFormatter()
+	registerChainFormatter()
+	registerConsoleFormatter()
+
+	_ = seelog.RegisterCustomFormatter(levelFormatter, func(param string) seelog.FormatterFunc {
+		return func(message string, l seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+			if display, _, ok := splitMarker(message); ok {
+				return display
+			}
+			return strings.ToUpper(l.String())
+		}
+	})
+	_ = seelog.RegisterCustomFormatter(messageFormatter, func(param string) seelog.FormatterFunc {
+		return func(message string, l seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+			_, m, _ := splitMarker(message)
+			return redact(m)
+		}
+	})
+}
+
+// separate a custom level display name from the message, any time
+// stamped from a clock is removed too
+func splitMarker(message string) (string, string, bool) {
+	_, message, _ = splitTime(message)
+	if !strings.HasPrefix(message, levelMarker) {
+		return "", message, false
+	}
+	n := strings.Index(message[len(levelMarker):], levelMarker)
+	if n < 0 {
+		return "", message, false
+	}
+	start := len(levelMarker)
+	return message[start : start+n], message[start+n+len(levelMarker):], true
+}
+
+// Log a simple string at any level, including custom levels
+// e.g.
+//   log.Log(noticeLevel, "a log message")
+//
+// level numbers that are not registered are ignored
+func (l *L) Log(levelNumber int, message string) {
+	l, b := usableLogger(l)
+	if nil == b {
+		return
+	}
+	if d, ok := b.enabled(levelNumber); ok {
+		b.output(d, l.formatPrefix+message)
+	}
+}
+
+// Log a formatted string with arguments like fmt.Sprintf() at any level
+// e.g.
+//   log.Logf(noticeLevel, "the value = %d", xValue)
+func (l *L) Logf(levelNumber int, format string, arguments ...interface{}) {
+	l, b := usableLogger(l)
+	if nil == b {
+		return
+	}
+	if d, ok := b.enabled(levelNumber); ok {
+		b.output(d, fmt.Sprintf(l.formatPrefix+format, arguments...))
+	}
+}
+
+// Log from a closure at any level, the closure will only be executed
+// if the level is enabled
+// e.g.
+//   log.Logc(noticeLevel, func() string {
+//       return fmt.Sprintf("the sin(%f) = %f", x, math.sin(x))
+//   })
+func (l *L) Logc(levelNumber int, closure func() string) {
+	l, b := usableLogger(l)
+	if nil == b {
+		return
+	}
+	if d, ok := b.enabled(levelNumber); ok {
+		b.output(d, l.formatPrefix+closure())
+	}
+}
+
+// check if a level is to be output by a channel
+func (b *binding) enabled(levelNumber int) (level.Definition, bool) {
+	if levelNumber >= level.OffLevel {
+		return level.Definition{}, false
+	}
+	d, ok := level.Lookup(levelNumber)
+	if !ok {
+		return d, false
+	}
+	return d, d.Unfiltered || b.levelNumber <= levelNumber
+}
+
+// send a message to seelog at the base level of a definition
+func (b *binding) output(d level.Definition, s string) {
+	if !d.Standard() {
+		s = levelMarker + d.Display + levelMarker + s
+	}
+	outputAt(b.log, d.Base(), s)
+}
+
+// send a message to seelog using a standard level number
+func outputAt(log seelog.LoggerInterface, levelNumber int, s string) {
+	switch levelNumber {
+	case level.TraceLevel:
+		log.Trace(s)
+	case level.DebugLevel:
+		log.Debug(s)
+	case level.InfoLevel:
+		log.Info(s)
+	case level.WarnLevel:
+		_ = log.Warn(s)
+	case level.ErrorLevel:
+		_ = log.Error(s)
+	default:
+		_ = log.Critical(s)
+	}
+}
@@ -6,6 +6,7 @@
 package logger
 
 import (
+	"crypto"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cihub/seelog"
@@ -25,10 +27,19 @@ import (
 // example of use (with structure tags for config file parsing)
 //   type AppConfiguration struct {
 //     …
-//     Logging logger.Configuration `libucl:"logging" hcl:"logging" json:"logging"`
+//     Logging logger.Configuration `libucl:"logging" hcl:"logging" json:"logging" yaml:"logging" toml:"logging"`
 //     …
 //   }
 //
+//   // or read the logging section of a YAML, TOML or JSON file with
+//   // defaults applied for omitted settings
+//   conf.Logging, err = logger.LoadConfigurationFile("app.yaml")
+//
+//   // optionally, environment variables e.g. APP_LOG_LEVELS=main=debug
+//   // take precedence over the configuration file
+//   conf.Logging, err = conf.Logging.WithEnvironment("APP_LOG")
+//   …
+//
 //   err := logger.Initialise(conf.Logging)
 //   if nil != err {  // if failed then display message and exit
 //     exitwithstatus.Message("logger error: %s", err)
@@ -38,23 +49,86 @@ import (
 // example of ucl/hcl configuration section
 //   logging {
 //     directory = "/var/lib/app/log"
+//     #create_directory = true # to create a missing directory tree
+//     #directory_mode = "0750" # for created directories (default 0750)
 //     file = "app.log"
+//     #file_mode = "0640" # for log files (default 0666 less umask)
+//     #owner = "app" # user and group for log files
+//     #group = "adm"
 //     size = 1048576
 //     count = 50
 //     #console = true # to duplicate messages to console (default false)
+//     #console_style = "pretty" # colours etc. when console is a terminal
+//     #timestamp = "rfc3339milli" # or unix, unixmilli or a time.Format layout holding the full date and time
+//     #utc = true # to write timestamps in UTC (default local time)
+//     #chain = true # to hash chain the records of the log file
+//     #redact {  # to remove secrets from messages
+//     #  patterns = ["[0-9a-f]{64}"]
+//     #  fields = ["token", "seed"]
+//     #  tags {
+//     #    rpc { fields = ["password"] }
+//     #  }
+//     #}
+//     #sampling {  # to limit noisy tags, see Sampling
+//     #  peer { type = "random", probability = 0.1 }
+//     #}
+//     #sampling_summary = "1m" # how often to report suppressed records
+//     #deduplicate = "10s" # to collapse identical messages within this time
+//     #output_levels {  # minimum level delivered to each output
+//     #  file = "debug"
+//     #  console = "warn"
+//     #}
+//     #strict = true # to reject unknown level names (default ignore them)
 //     levels {
 //       DEFAULT = "info"
 //       system = "error"
 //       main = "warn"
+//       "p2p.*" = "debug"  # all descendants e.g. p2p.peer.handshake
 //     }
 //   }
 type Configuration struct {
-	Directory string            `libucl:"directory" hcl:"directory" json:"directory"`
-	File      string            `libucl:"file" hcl:"file" json:"file"`
-	Size      int               `libucl:"size" hcl:"size" json:"size"`
-	Count     int               `libucl:"count" hcl:"count" json:"count"`
-	Levels    map[string]string `libucl:"levels" hcl:"levels" json:"levels"`
-	Console   bool              `libucl:"console" hcl:"console" json:"console"`
+	Directory    string            `libucl:"directory" hcl:"directory" json:"directory" yaml:"directory,omitempty" toml:"directory,omitempty"`
+	File         string            `libucl:"file" hcl:"file" json:"file" yaml:"file,omitempty" toml:"file,omitempty"`
+	Size         int               `libucl:"size" hcl:"size" json:"size" yaml:"size,omitempty" toml:"size,omitempty,omitzero"`
+	Count        int               `libucl:"count" hcl:"count" json:"count" yaml:"count,omitempty" toml:"count,omitempty,omitzero"`
+	Levels       map[string]string `libucl:"levels" hcl:"levels" json:"levels" yaml:"levels,omitempty" toml:"levels,omitempty"`
+	Console      bool              `libucl:"console" hcl:"console" json:"console" yaml:"console,omitempty" toml:"console,omitempty"`
+	ConsoleStyle string            `libucl:"console_style" hcl:"console_style" json:"console_style" yaml:"console_style,omitempty" toml:"console_style,omitempty"`
+	Timestamp    string            `libucl:"timestamp" hcl:"timestamp" json:"timestamp" yaml:"timestamp,omitempty" toml:"timestamp,omitempty"`
+	UTC          bool              `libucl:"utc" hcl:"utc" json:"utc" yaml:"utc,omitempty" toml:"utc,omitempty"`
+	Chain        bool              `libucl:"chain" hcl:"chain" json:"chain" yaml:"chain,omitempty" toml:"chain,omitempty"`
+	OutputLevels map[string]string `libucl:"output_levels" hcl:"output_levels" json:"output_levels" yaml:"output_levels,omitempty" toml:"output_levels,omitempty"`
+
+	CreateDirectory bool   `libucl:"create_directory" hcl:"create_directory" json:"create_directory" yaml:"create_directory,omitempty" toml:"create_directory,omitempty"`
+	DirectoryMode   string `libucl:"directory_mode" hcl:"directory_mode" json:"directory_mode" yaml:"directory_mode,omitempty" toml:"directory_mode,omitempty"`
+	FileMode        string `libucl:"file_mode" hcl:"file_mode" json:"file_mode" yaml:"file_mode,omitempty" toml:"file_mode,omitempty"`
+	Owner           string `libucl:"owner" hcl:"owner" json:"owner" yaml:"owner,omitempty" toml:"owner,omitempty"`
+	Group           string `libucl:"group" hcl:"group" json:"group" yaml:"group,omitempty" toml:"group,omitempty"`
+
+	// fail if Levels contains unknown level names instead of
+	// ignoring them
+	Strict bool `libucl:"strict" hcl:"strict" json:"strict" yaml:"strict,omitempty" toml:"strict,omitempty"`
+
+	Redact RedactionConfiguration `libucl:"redact" hcl:"redact" json:"redact" yaml:"redact,omitempty" toml:"redact,omitempty"`
+
+	Sampling        map[string]Sampling `libucl:"sampling" hcl:"sampling" json:"sampling" yaml:"sampling,omitempty" toml:"sampling,omitempty"`
+	SamplingSummary string              `libucl:"sampling_summary" hcl:"sampling_summary" json:"sampling_summary" yaml:"sampling_summary,omitempty" toml:"sampling_summary,omitempty"`
+	Deduplicate     string              `libucl:"deduplicate" hcl:"deduplicate" json:"deduplicate" yaml:"deduplicate,omitempty" toml:"deduplicate,omitempty"`
+
+	// optional source of time for timestamps, e.g. a fixed time
+	// for tests or recorded times for replay tools; it is read once
+	// as each message is logged
+	Clock Clock `libucl:"-" hcl:"-" json:"-" yaml:"-" toml:"-"`
+
+	// optional Ed25519 key to sign each closed log file segment,
+	// e.g. an ed25519.PrivateKey
+	Signer crypto.Signer `libucl:"-" hcl:"-" json:"-" yaml:"-" toml:"-"`
+
+	// optional destination for the messages instead of the log file,
+	// e.g. a loggertest.Sink; Directory, File, Size and Count are then
+	// not used and each message is delivered before the logging call
+	// returns
+	Receiver Receiver `libucl:"-" hcl:"-" json:"-" yaml:"-" toml:"-"`
 }
 
 // some restrictions on sizes
@@ -87,20 +161,41 @@ type L struct {
 	tag          string
 	formatPrefix string
 	textPrefix   string
-	level        string
-	levelNumber  int
-	log          seelog.LoggerInterface
+	references   int
+	detached     bool
+	discard      bool
+	binding      atomic.Pointer[binding]
+}
+
+// the output and level of a channel, a new binding is published
+// whenever either changes so that each message is handled with a
+// consistent pair
+type binding struct {
+	log         seelog.LoggerInterface
+	level       string
+	levelNumber int
+	generation  int64 // of the output the binding uses
 }
 
 // LogLevels - log levels
 type LogLevels struct {
-	Levels []Level `json:"levels"`
+	Levels  []Level       `json:"levels"`
+	Outputs []OutputLevel `json:"outputs,omitempty"`
 }
 
 // Level - log level info
 type Level struct {
-	Tag      string `json:"tag"`
-	LogLevel string `json:"category"`
+	Tag      string         `json:"tag"`
+	LogLevel string         `json:"category"`
+	Count    int            `json:"count"`
+	Override *LevelOverride `json:"override,omitempty"`
+}
+
+// LevelOverride - temporary level override info
+type LevelOverride struct {
+	Original string    `json:"original"`
+	Level    string    `json:"level"`
+	Expires  time.Time `json:"expires"`
 }
 
 type outputTarget int
@@ -117,20 +212,46 @@ type loggers struct {
 	initialised bool
 	data        []*L
 	output      outputTarget
+	overrides   map[string]*override
+	chainFile   string
+	logFile     string
+	signer      crypto.Signer
+
+	sampling     map[string]Sampling
+	samplers     map[string]*sampler
+	stopSampling func()
+
+	dedupWindow time.Duration
+	dedupers    map[string]*deduplicator
+
+	outputLevels map[string]string
+
+	// incremented whenever the output is replaced so that channels
+	// rebind to it on their next use
+	generation atomic.Int64
+}
+
+// a temporary level for a tag, restored to original on expiry
+type override struct {
+	original string
+	level    string
+	expires  time.Time
+	timer    *time.Timer
 }
 
 var globalData loggers
 
 // default set output to standard out
 func init() {
+	registerFormatters()
+
 	globalData.output = stdOut
 	stdLogger := defaultLogger()
 
 	_ = seelog.ReplaceLogger(stdLogger)
 	// ensure that the global critical/panic functions always written
 	globalData.globalLog = New("PANIC")
-	globalData.globalLog.level = level.Critical
-	globalData.globalLog.levelNumber = level.ValidLevels[level.Critical]
+	globalData.globalLog.setLevel(level.Critical)
 }
 
 func defaultLogger() seelog.LoggerInterface {
@@ -144,9 +265,9 @@ func defaultLogger() seelog.LoggerInterface {
 			      <console />
               </outputs>
               <formats>
-                  <format id="all" format="%%Date %%Time [%%LEVEL] %%Msg%%n" />
+                  <format id="all" format="%s" />
               </formats>
-          </seelog>`)
+          </seelog>`, logFormat)
 
 	logger, _ := seelog.LoggerFromConfigAsString(config)
 	return logger
@@ -158,142 +279,244 @@ func Initialise(configuration Configuration) error {
 		return errors.New("logger is already initialised")
 	}
 
-	globalData.output = fileOut
-
-	if "" == configuration.Directory {
-		return errors.New("Directory cannot be empty")
+	// unknown level names are only errors in strict mode
+	if errs := configuration.validate(configuration.Strict); 0 != len(errs) {
+		return errs
 	}
 
-	if "" == configuration.File {
-		return errors.New("File cannot be empty")
+	directoryMode, permissions, err := validatePermissions(configuration)
+	if nil != err {
+		return err
 	}
 
-	d, f := path.Split(configuration.File)
-	if "" != d && f != configuration.File {
-		return fmt.Errorf("File: %q cannot be a path name", configuration.File)
-	}
+	filepath := ""
+	if nil == configuration.Receiver {
+		filepath = path.Join(configuration.Directory, configuration.File)
 
-	if configuration.Size < minimumSize {
-		return fmt.Errorf("Size: %d cannot be less than: %d", configuration.Size, minimumSize)
+		err = prepareDirectory(configuration.Directory, configuration.CreateDirectory, directoryMode, filepath, permissions)
+		if nil != err {
+			return err
+		}
 	}
 
-	if configuration.Count < minimumCount {
-		return fmt.Errorf("Count: %d cannot be less than: %d", configuration.Count, minimumCount)
+	globalData.Lock()
+	for tag, l := range configuration.Levels {
+		// make sure that levelMap only contains correct data
+		// by ignoring invalid levels
+		if _, ok := level.Number(l); ok {
+			levelMap[tag] = l
+		}
 	}
+	globalData.Unlock()
 
-	info, err := os.Lstat(configuration.Directory)
+	err = setRedaction(configuration.Redact)
 	if nil != err {
 		return err
 	}
-	if !info.IsDir() {
-		return fmt.Errorf("Directory: %q does not exist", configuration.Directory)
+
+	samplingSummary, err := validateSampling(configuration.Sampling, configuration.SamplingSummary)
+	if nil != err {
+		return err
 	}
-	//permission := info.Mode().Perm()
 
-	lockFile := path.Join(configuration.Directory, "log.test")
-	os.Remove(lockFile)
-	fd, err := os.Create(lockFile)
+	dedupWindow, err := validateDeduplicate(configuration.Deduplicate)
 	if nil != err {
 		return err
 	}
-	defer fd.Close()
-	defer os.Remove(lockFile)
-	n, err := fd.Write([]byte("0123456789"))
+
+	outputLevels, err := validateOutputLevels(configuration.OutputLevels, configuration.Console)
 	if nil != err {
 		return err
 	}
-	if 10 != n {
-		return errors.New("unable to write to logging files")
+
+	optionalConsole := ""
+	if configuration.Console {
+		formatID, err := consoleFormatID(configuration.ConsoleStyle)
+		if nil != err {
+			return err
+		}
+		optionalConsole = filterOutput(outputLevels[OutputConsole], `<console formatid="`+formatID+`" />`)
+	} else {
+		delete(outputLevels, OutputConsole)
 	}
 
-	for tag, l := range configuration.Levels {
-		// make sure that levelMap only contains correct data
-		// by ignoring invalid levels
-		if _, ok := level.ValidLevels[l]; ok {
-			levelMap[tag] = l
+	// rotation of the log file is done here rather than by seelog so
+	// that each new file has its permissions before anything is
+	// written to it
+	logFile := &rollingFile{
+		filepath:    filepath,
+		size:        int64(configuration.Size),
+		count:       configuration.Count,
+		permissions: permissions,
+	}
+	if nil != configuration.Signer {
+		if err := validSigner(configuration.Signer); nil != err {
+			return err
 		}
+
+		// the current file will be appended to, so any
+		// signature from a previous run no longer applies
+		os.Remove(filepath + signatureSuffix)
+
+		logFile.rotated = append(logFile.rotated, segmentSigner(configuration.Signer, filepath))
+	}
+	params := &seelog.CfgParseParams{
+		CustomReceiverProducers: map[string]seelog.CustomReceiverProducer{
+			fileReceiver: func(seelog.CustomReceiverInitArgs) (seelog.CustomReceiver, error) {
+				return logFile, nil
+			},
+		},
 	}
 
-	optionalConsole := ""
-	if configuration.Console {
-		optionalConsole = "<console />"
+	fileFormat := "all"
+	if configuration.Chain {
+		fileFormat = "chained"
+		err := resumeChain(filepath)
+		if nil != err {
+			return err
+		}
+	}
+
+	loggerType := `type="adaptive" mininterval="2000000" maxinterval="100000000" critmsgcount="500"`
+	fileOutput := `<custom name="` + fileReceiver + `" formatid="` + fileFormat + `" />`
+	if nil != configuration.Receiver {
+		// synchronous, so that a test sees its messages at once
+		loggerType = `type="sync"`
+		fileOutput = `<custom name="` + recordReceiver + `" formatid="raw" />`
+		params.CustomReceiverProducers[recordReceiver] = func(seelog.CustomReceiverInitArgs) (seelog.CustomReceiver, error) {
+			return &receiverAdapter{
+				receiver: configuration.Receiver,
+				global:   true,
+			}, nil
+		}
 	}
 
-	filepath := path.Join(configuration.Directory, configuration.File)
 	config := fmt.Sprintf(`
-          <seelog type="adaptive"
-                  mininterval="2000000"
-                  maxinterval="100000000"
-                  critmsgcount="500"
-                  minlevel="trace">
+          <seelog %s minlevel="trace">
               <outputs formatid="all">
-                  <rollingfile type="size" filename="%s" maxsize="%d" maxrolls="%d" />
+                  %s
                   %s
               </outputs>
               <formats>
-                  <format id="all" format="%%Date %%Time [%%LEVEL] %%Msg%%n" />
+                  <format id="all" format="%s" />
+                  <format id="chained" format="%s" />
+                  <format id="raw" format="%%Msg" />
+                  <format id="console" format="%s" />
               </formats>
-          </seelog>`, filepath, configuration.Size, configuration.Count, optionalConsole)
+          </seelog>`, loggerType, filterOutput(outputLevels[OutputFile], fileOutput), optionalConsole, logFormat, chainFormat, consoleFormat)
 
-	logger, err := seelog.LoggerFromConfigAsString(config)
+	logger, err := seelog.LoggerFromParamConfigAsString(config, params)
 	if err != nil {
 		return err
 	}
-	err = seelog.ReplaceLogger(logger)
+	setTimestamps(configuration.Timestamp, configuration.UTC)
+	globalData.Lock()
+	err = seelog.ReplaceLogger(withClock(logger, configuration.Clock))
+	globalData.Unlock()
 	if nil == err {
 		seelog.Current.Warn("LOGGER: ===== Logging system started =====")
+		globalData.output = fileOut
 		globalData.initialised = true
 
-		// ensure that the global critical/panic functions always write to the log file
-		globalData.globalLog = New("PANIC")
-		globalData.globalLog.level = level.Critical
-		globalData.globalLog.levelNumber = level.ValidLevels[level.Critical]
+		globalData.Lock()
+		startSampling(configuration.Sampling, samplingSummary)
+		globalData.dedupWindow = dedupWindow
+		globalData.dedupers = nil
+		globalData.outputLevels = outputLevels
+		globalData.Unlock()
+		if configuration.Chain {
+			globalData.chainFile = filepath
+		}
+		globalData.logFile = filepath
+		globalData.signer = configuration.Signer
+
+		// bind all channels, including the one for the global
+		// critical/panic functions, to the log file and the new levels
+		globalData.generation.Add(1)
+		globalData.Lock()
+		rebindAll()
+		globalData.Unlock()
 	}
 	return err
 }
 
 // flush all channels and let log message goes to standard out
 func Finalise() {
+	globalData.Lock()
+	flushDuplicates()
+	globalData.dedupWindow = 0
+	globalData.dedupers = nil
+	globalData.outputLevels = nil
+	globalData.Unlock()
+
+	stopSampling()
+
 	_ = seelog.Current.Warn("LOGGER: ===== Logging system stopped =====")
 	seelog.Flush()
 
+	if "" != globalData.chainFile {
+		_ = writeChainHead(globalData.chainFile)
+		globalData.chainFile = ""
+	}
+	if nil != globalData.signer {
+		_ = signSegment(globalData.signer, globalData.logFile)
+		globalData.signer = nil
+	}
+
+	// settings may remain from a failed Initialise
+	setTimestamps(TimestampDefault, false)
+	clearRedaction()
+
 	// if log message goes to file, make it back to standard output
 	if globalData.output == fileOut {
 		globalData.initialised = false
 		globalData.output = stdOut
+		globalData.Lock()
 		_ = seelog.ReplaceLogger(defaultLogger())
-
-		globalData.globalLog = New("PANIC")
-		globalData.globalLog.level = level.Critical
-		globalData.globalLog.levelNumber = level.ValidLevels[level.Critical]
+		globalData.Unlock()
+		globalData.generation.Add(1)
 	}
 
-	globalData.data = globalData.data[:0]
+	// channels still in use keep their registration, only their
+	// overrides end
+	globalData.Lock()
+	for tag, o := range globalData.overrides {
+		o.timer.Stop()
+		delete(globalData.overrides, tag)
+	}
+	rebindAll()
+	globalData.Unlock()
 }
 
 // flush all channels
 func Flush() {
+	globalData.Lock()
+	flushDuplicates()
+	globalData.Unlock()
+
 	seelog.Flush()
 }
 
 // Open a new logging channel with a specified tag
+//
+// all calls with the same tag share a single channel, each call should
+// be balanced by a Close when the channel is no longer required
+//
+// a channel may be created before Initialise, e.g. in a package level
+// variable, it uses the output and levels in effect when it logs
 func New(tag string) *L {
-	// if log message goes to file, then Initialise should be called
-	if globalData.output == fileOut && !globalData.initialised {
-		panic("logger.New Initialise was not called")
-	}
-
 	// map % -> %% to be printf safe
 	s := strings.Split(tag, "%")
 	j := strings.Join(s, "%%")
 
-	// determine the level
-	l, ok := levelMap[tag]
-	if !ok {
-		l, ok = levelMap[DefaultTag]
-	}
-	if !ok {
-		l = DefaultLevel
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	for _, l := range globalData.data {
+		if l.tag == tag {
+			l.references += 1
+			return l
+		}
 	}
 
 	// create a logger channel
@@ -301,23 +524,117 @@ func New(tag string) *L {
 		tag:          tag, // for referencing default level
 		formatPrefix: j + tagSuffix,
 		textPrefix:   tag + tagSuffix,
-		level:        l,
-		levelNumber:  level.ValidLevels[l], // level is validated so get a non-zero value
-		log:          seelog.Current,
+		references:   1,
 	}
+	rebind(ptr)
 
-	globalData.Lock()
 	globalData.data = append(globalData.data, ptr)
-	defer globalData.Unlock()
 
 	return ptr
 }
 
+// the binding to use for a message, the channel is first bound to the
+// current output and levels if the output was replaced since the
+// channel was created or last used
+//
+// nil for a channel that was not obtained from New or NewDetached
+func (l *L) current() *binding {
+	b := l.binding.Load()
+	if nil == b || l.detached || b.generation == globalData.generation.Load() {
+		return b
+	}
+
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	return rebind(l)
+}
+
+// bind every registered channel to the current output and levels
+// globalData must be locked by caller
+func rebindAll() {
+	for _, l := range globalData.data {
+		rebind(l)
+	}
+}
+
+// bind a channel to the current output and its level, an active
+// override takes precedence over the configured level and the global
+// channel keeps its level
+// globalData must be locked by caller
+func rebind(l *L) *binding {
+	b := &binding{
+		generation: globalData.generation.Load(), // before the output so a later change is seen
+		log:        channelLog(l.tag),
+	}
+	if previous := l.binding.Load(); nil != previous && l == globalData.globalLog {
+		b.level = previous.level
+	} else if o, ok := globalData.overrides[l.tag]; ok {
+		b.level = o.level
+	} else {
+		b.level = levelForTag(l.tag)
+	}
+	b.levelNumber, _ = level.Number(b.level) // level is validated so get a non-zero value
+	l.binding.Store(b)
+	return b
+}
+
+// change the level of a channel, keeping its output
+// globalData must be locked by caller
+func (l *L) setLevel(name string) {
+	b := *l.binding.Load()
+	b.level = name
+	b.levelNumber, _ = level.Number(name)
+	l.binding.Store(&b)
+}
+
+// the output for a channel: the current seelog logger with any
+// deduplication and sampling for the tag
+// globalData must be locked by caller
+func channelLog(tag string) seelog.LoggerInterface {
+	filters := make([]filter, 0, 2)
+	if d := deduplicatorForTag(tag); nil != d {
+		filters = append(filters, d)
+	}
+	if s := samplerForTag(tag); nil != s {
+		filters = append(filters, s)
+	}
+	return withFilters(seelog.Current, filters...)
+}
+
 // flush messages
 func (l *L) Flush() {
 	Flush()
 }
 
+// release a channel obtained from New
+//
+// the channel is removed from the registry when all its users have
+// closed it, but it remains usable by any remaining references
+func (l *L) Close() {
+	if nil == l {
+		return
+	}
+
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	if l.references <= 0 {
+		return
+	}
+	l.references -= 1
+	if l.references > 0 {
+		return
+	}
+
+	for i, item := range globalData.data {
+		if item == l {
+			globalData.data = append(globalData.data[:i], globalData.data[i+1:]...)
+			break
+		}
+	}
+}
+
 // global logging message
 func Critical(message string) {
 	globalData.globalLog.Critical(message)
@@ -353,19 +670,33 @@ func PanicIfError(message string, err error) {
 }
 
 // ListLevels - return log level info in json format
-// it's not lock protected, because it should be low frequency to list log
-// levels
+// it is lock protected since level overrides expire asynchronously
 func ListLevels() ([]byte, error) {
+	globalData.Lock()
+	defer globalData.Unlock()
+
 	levels := make([]Level, 0)
 
 	for _, l := range globalData.data {
-		levels = append(levels, Level{
+		lv := Level{
 			Tag:      l.tag,
-			LogLevel: l.level,
-		})
+			LogLevel: l.binding.Load().level,
+			Count:    l.references,
+		}
+		if o, ok := globalData.overrides[l.tag]; ok {
+			lv.Override = &LevelOverride{
+				Original: o.original,
+				Level:    o.level,
+				Expires:  o.expires,
+			}
+		}
+		levels = append(levels, lv)
 	}
 
-	ll := LogLevels{Levels: levels}
+	ll := LogLevels{
+		Levels:  levels,
+		Outputs: listOutputLevels(),
+	}
 	bs, err := json.Marshal(ll)
 	if nil != err {
 		return []byte{}, err
@@ -375,33 +706,136 @@ func ListLevels() ([]byte, error) {
 }
 
 // UpdateTagLogLevel - update log level for specific tag
+//
+// the new level is recorded so that all existing and future channels
+// with the tag use it.  The tag can be a pattern like "p2p.*" to set a
+// whole subtree at once, or DefaultTag to change the level of all tags
+// without an explicit setting
 func UpdateTagLogLevel(tag, newLevel string) error {
+	if _, ok := level.Number(newLevel); !ok {
+		return fmt.Errorf("level %s invalid", newLevel)
+	}
+
 	globalData.Lock()
 	defer globalData.Unlock()
 
-	for i, l := range globalData.data {
-		if l.tag == tag {
-			if num, ok := level.ValidLevels[newLevel]; !ok {
-				return fmt.Errorf("level %s invalid", newLevel)
-			} else {
-				globalData.data[i].levelNumber = num
-				globalData.data[i].level = newLevel
-				return nil
+	// a pattern replaces any more specific settings in its subtree
+	if strings.HasSuffix(tag, tagWildcard) {
+		for k := range levelMap {
+			if k != tag && tagMatches(tag, strings.TrimSuffix(k, tagWildcard)) {
+				delete(levelMap, k)
 			}
 		}
 	}
+	levelMap[tag] = newLevel
+
+	for _, l := range globalData.data {
+		// the global channel always stays at critical
+		if l == globalData.globalLog && !tagMatches(tag, l.tag) {
+			continue
+		}
 
-	return fmt.Errorf("tag %s not found", tag)
+		n := levelForTag(l.tag)
+		if o, ok := globalData.overrides[l.tag]; ok {
+			if !tagMatches(tag, l.tag) {
+				// keep the override, but restore to the new level
+				o.original = n
+				continue
+			}
+
+			// an explicit update replaces any temporary override
+			o.timer.Stop()
+			delete(globalData.overrides, l.tag)
+		}
+		l.setLevel(n)
+	}
+
+	return nil
 }
 
-func validLogger(l *L) bool {
-	if globalData.output == stdOut {
-		return true
+// OverrideTagLogLevel - temporarily change the log level for a
+// specific tag, the previous level is restored after duration
+//
+// overriding a tag that is already overridden replaces the level and
+// expiry but keeps the original level
+func OverrideTagLogLevel(tag, newLevel string, duration time.Duration) error {
+	if _, ok := level.Number(newLevel); !ok {
+		return fmt.Errorf("level %s invalid", newLevel)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("duration %s invalid", duration)
+	}
+
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	original := ""
+	for _, l := range globalData.data {
+		if l.tag == tag {
+			original = l.binding.Load().level
+			break
+		}
+	}
+	if "" == original {
+		return fmt.Errorf("tag %s not found", tag)
+	}
+
+	if o, ok := globalData.overrides[tag]; ok {
+		o.timer.Stop()
+		original = o.original
+	}
+
+	o := &override{
+		original: original,
+		level:    newLevel,
+		expires:  time.Now().Add(duration),
 	}
+	o.timer = time.AfterFunc(duration, func() {
+		globalData.Lock()
+		defer globalData.Unlock()
+		if globalData.overrides[tag] == o {
+			restoreOverride(tag, o)
+		}
+	})
 
-	if !globalData.initialised || nil == l {
-		return false
+	if nil == globalData.overrides {
+		globalData.overrides = make(map[string]*override)
 	}
+	globalData.overrides[tag] = o
+	setChannelLevels(tag, newLevel)
 
-	return true
+	return nil
+}
+
+// CancelTagLogLevelOverride - restore the original log level of a tag
+// before its override expires
+func CancelTagLogLevelOverride(tag string) error {
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	o, ok := globalData.overrides[tag]
+	if !ok {
+		return fmt.Errorf("tag %s has no override", tag)
+	}
+	o.timer.Stop()
+	restoreOverride(tag, o)
+
+	return nil
+}
+
+// remove an override and put back the original level
+// globalData must be locked by caller
+func restoreOverride(tag string, o *override) {
+	delete(globalData.overrides, tag)
+	setChannelLevels(tag, o.original)
+}
+
+// set the level of every channel with a specific tag
+// globalData must be locked by caller
+func setChannelLevels(tag, newLevel string) {
+	for _, l := range globalData.data {
+		if l.tag == tag {
+			l.setLevel(newLevel)
+		}
+	}
 }
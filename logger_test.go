@@ -7,22 +7,32 @@ package logger_test
 
 import (
 	"bufio"
+	"compress/gzip"
+	"crypto/ed25519"
 	"encoding/json"
 	"io"
 	"os"
 	"path"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/bitmark-inc/logger"
+	"github.com/bitmark-inc/logger/level"
+	"github.com/bitmark-inc/logger/loggertest"
 )
 
 var testLevelMap = map[string]string{
-	"main": "debug",
-	"aux":  "warn",
+	"main":       "debug",
+	"aux":        "warn",
+	"p2p":        "info",
+	"p2p.*":      "debug",
+	"p2p.peer.*": "trace",
 }
 
 const (
@@ -35,15 +45,18 @@ const (
 func removeLogFiles() {
 	pathName := path.Join(logDirectory, logFileName)
 	os.Remove(pathName)
+	os.Remove(pathName + ".chain")
+	os.Remove(pathName + ".sig")
 	for i := 0; i <= logNumberOfFiles; i += 1 {
 		os.Remove(pathName + "." + strconv.Itoa(i))
+		os.Remove(pathName + "." + strconv.Itoa(i) + ".sig")
+		os.Remove(pathName + "." + strconv.Itoa(i) + ".gz")
 	}
 	os.Remove(logDirectory)
 }
 
-func setup(t *testing.T) {
-	removeLogFiles()
-	os.Mkdir(logDirectory, 0770)
+// the standard test configuration changed by each modify function
+func testConfiguration(modify ...func(c *logger.Configuration)) logger.Configuration {
 	c := logger.Configuration{
 		Directory: logDirectory,
 		File:      logFileName,
@@ -51,20 +64,43 @@ func setup(t *testing.T) {
 		Count:     logNumberOfFiles,
 		Levels:    testLevelMap,
 	}
+	for _, m := range modify {
+		m(&c)
+	}
+	return c
+}
+
+// start logging to an empty log directory
+func setup(t *testing.T, modify ...func(c *logger.Configuration)) {
+	removeLogFiles()
+	os.Mkdir(logDirectory, 0770)
+	startLogging(t, modify...)
+}
 
-	err := logger.Initialise(c)
+// start logging without removing existing files
+func startLogging(t *testing.T, modify ...func(c *logger.Configuration)) {
+	err := logger.Initialise(testConfiguration(modify...))
 	if err != nil {
 		t.Fatalf("Logger setup failed with error: %v", err)
 	}
 }
 
+// the error from a configuration that Initialise should reject, the
+// output is returned to standard output
+func initialiseError(modify ...func(c *logger.Configuration)) error {
+	removeLogFiles()
+	os.Mkdir(logDirectory, 0770)
+	err := logger.Initialise(testConfiguration(modify...))
+	logger.Finalise()
+	return err
+}
+
 func teardown() {
 	removeLogFiles()
 }
 
 func TestLevels(t *testing.T) {
-	setup(t)
-	defer teardown()
+	sink := loggertest.Capture(t, testConfiguration())
 
 	mainLog := logger.New("main")
 	auxLog := logger.New("aux")
@@ -83,17 +119,31 @@ func TestLevels(t *testing.T) {
 	auxLog.Error("This should log")
 	auxLog.Critical("This should log")
 
-	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
-2014-08-12 10:44:35 [DEBUG] main: This should log
-2014-08-12 10:44:35 [INFO] main: This should log
-2014-08-12 10:44:35 [WARN] main: This should log
-2014-08-12 10:44:35 [ERROR] main: This should log
-2014-08-12 10:44:35 [CRITICAL] main: This should log
-2014-08-12 10:44:35 [WARN] aux: This should log
-2014-08-12 10:44:35 [ERROR] aux: This should log
-2014-08-12 10:44:35 [CRITICAL] aux: This should log
-2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
-`)
+	expected := []struct {
+		level int
+		tag   string
+	}{
+		{level.DebugLevel, "main"},
+		{level.InfoLevel, "main"},
+		{level.WarnLevel, "main"},
+		{level.ErrorLevel, "main"},
+		{level.CriticalLevel, "main"},
+		{level.WarnLevel, "aux"},
+		{level.ErrorLevel, "aux"},
+		{level.CriticalLevel, "aux"},
+	}
+	records := sink.Records()
+	if !assert.Equal(t, 1+len(expected), len(records), "wrong record count") {
+		return
+	}
+	assert.Equal(t, "LOGGER", records[0].Tag, "wrong start tag")
+	assert.Equal(t, "===== Logging system started =====", records[0].Message, "wrong start message")
+	for i, e := range expected {
+		r := records[1+i]
+		assert.Equal(t, level.Level(e.level), r.Level, "wrong level: %d", i)
+		assert.Equal(t, e.tag, r.Tag, "wrong tag: %d", i)
+		assert.Equal(t, "This should log", r.Message, "wrong message: %d", i)
+	}
 }
 
 func TestClosure(t *testing.T) {
@@ -205,3 +255,1318 @@ func TestUpdateTagLogLevel(t *testing.T) {
 
 	logger.Panic("this should log")
 }
+
+func findLevel(t *testing.T, tag string) logger.Level {
+	bs, err := logger.ListLevels()
+	assert.Nil(t, err, "wrong ListLevels")
+
+	var s logger.LogLevels
+	err = json.Unmarshal(bs, &s)
+	assert.Nil(t, err, "wrong bytes unmarshal")
+
+	for _, l := range s.Levels {
+		if l.Tag == tag {
+			return l
+		}
+	}
+	t.Fatalf("tag %s not found", tag)
+	return logger.Level{}
+}
+
+func TestOverrideTagLogLevel(t *testing.T) {
+	logger.New("override")
+
+	err := logger.UpdateTagLogLevel("override", "warn")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+
+	err = logger.OverrideTagLogLevel("override", "trace", 50*time.Millisecond)
+	assert.Nil(t, err, "wrong OverrideTagLogLevel")
+
+	l := findLevel(t, "override")
+	assert.Equal(t, "trace", l.LogLevel, "wrong overridden level")
+	if assert.NotNil(t, l.Override, "missing override") {
+		assert.Equal(t, "warn", l.Override.Original, "wrong original level")
+		assert.Equal(t, "trace", l.Override.Level, "wrong override level")
+		assert.False(t, l.Override.Expires.IsZero(), "missing expiry")
+	}
+
+	// a second override keeps the original level
+	err = logger.OverrideTagLogLevel("override", "debug", 50*time.Millisecond)
+	assert.Nil(t, err, "wrong OverrideTagLogLevel")
+	l = findLevel(t, "override")
+	assert.Equal(t, "debug", l.LogLevel, "wrong overridden level")
+	assert.Equal(t, "warn", l.Override.Original, "wrong original level")
+
+	time.Sleep(150 * time.Millisecond)
+
+	l = findLevel(t, "override")
+	assert.Equal(t, "warn", l.LogLevel, "level not restored")
+	assert.Nil(t, l.Override, "override not removed")
+}
+
+func TestOverrideExpiryWhileLogging(t *testing.T) {
+	l := logger.New("expiring")
+	defer l.Close()
+
+	err := logger.UpdateTagLogLevel("expiring", "error")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+
+	err = logger.OverrideTagLogLevel("expiring", "debug", 20*time.Millisecond)
+	assert.Nil(t, err, "wrong OverrideTagLogLevel")
+
+	// the override expires on its own goroutine while the channel
+	// is in use
+	stop := time.Now().Add(60 * time.Millisecond)
+	for time.Now().Before(stop) {
+		l.Trace("This should not log")
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, "error", findLevel(t, "expiring").LogLevel, "level not restored")
+}
+
+func TestCancelTagLogLevelOverride(t *testing.T) {
+	logger.New("cancel")
+
+	err := logger.UpdateTagLogLevel("cancel", "error")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+
+	err = logger.OverrideTagLogLevel("cancel", "trace", time.Hour)
+	assert.Nil(t, err, "wrong OverrideTagLogLevel")
+
+	err = logger.CancelTagLogLevelOverride("cancel")
+	assert.Nil(t, err, "wrong CancelTagLogLevelOverride")
+
+	l := findLevel(t, "cancel")
+	assert.Equal(t, "error", l.LogLevel, "level not restored")
+	assert.Nil(t, l.Override, "override not removed")
+
+	err = logger.CancelTagLogLevelOverride("cancel")
+	assert.NotNil(t, err, "cancel without override should fail")
+
+	err = logger.OverrideTagLogLevel("cancel", "bad", time.Hour)
+	assert.NotNil(t, err, "invalid level should fail")
+
+	err = logger.OverrideTagLogLevel("no-such-tag", "trace", time.Hour)
+	assert.NotNil(t, err, "unknown tag should fail")
+}
+
+func TestHierarchicalLevels(t *testing.T) {
+	setup(t)
+	defer teardown()
+	defer logger.Finalise()
+
+	logger.New("p2p")
+	logger.New("p2p.listener")
+	logger.New("p2p.peer.handshake")
+	logger.New("p2p2")
+
+	assert.Equal(t, "info", findLevel(t, "p2p").LogLevel, "wrong p2p level")
+	assert.Equal(t, "debug", findLevel(t, "p2p.listener").LogLevel, "wrong p2p.listener level")
+	assert.Equal(t, "trace", findLevel(t, "p2p.peer.handshake").LogLevel, "wrong p2p.peer.handshake level")
+	assert.Equal(t, "error", findLevel(t, "p2p2").LogLevel, "wrong p2p2 level")
+
+	err := logger.UpdateTagLogLevel("p2p.*", "warn")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+
+	assert.Equal(t, "info", findLevel(t, "p2p").LogLevel, "wrong p2p level")
+	assert.Equal(t, "warn", findLevel(t, "p2p.listener").LogLevel, "wrong p2p.listener level")
+	assert.Equal(t, "warn", findLevel(t, "p2p.peer.handshake").LogLevel, "wrong p2p.peer.handshake level")
+	assert.Equal(t, "error", findLevel(t, "p2p2").LogLevel, "wrong p2p2 level")
+
+	// later channels in the subtree also use the new level
+	logger.New("p2p.peer.other")
+	assert.Equal(t, "warn", findLevel(t, "p2p.peer.other").LogLevel, "wrong p2p.peer.other level")
+}
+
+func TestUpdateTagLogLevelAllChannels(t *testing.T) {
+	setup(t)
+	defer teardown()
+	defer logger.Finalise()
+
+	auxLog := logger.New("aux")
+
+	err := logger.UpdateTagLogLevel("aux", "info")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+	assert.Equal(t, "info", findLevel(t, "aux").LogLevel, "channel not updated")
+
+	// close so a future channel is created afresh
+	auxLog.Close()
+	logger.New("aux")
+	assert.Equal(t, "info", findLevel(t, "aux").LogLevel, "future channel not updated")
+
+	// a tag without any channel yet is accepted
+	err = logger.UpdateTagLogLevel("future", "trace")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+	logger.New("future")
+	assert.Equal(t, "trace", findLevel(t, "future").LogLevel, "wrong future level")
+
+	err = logger.UpdateTagLogLevel("aux", "bad")
+	assert.NotNil(t, err, "invalid level should fail")
+}
+
+func TestUpdateDefaultLogLevel(t *testing.T) {
+	setup(t)
+	defer teardown()
+	defer logger.Finalise()
+	defer logger.UpdateTagLogLevel(logger.DefaultTag, logger.DefaultLevel)
+
+	logger.New("main")
+	logger.New("unset")
+	assert.Equal(t, "error", findLevel(t, "unset").LogLevel, "wrong unset level")
+
+	err := logger.UpdateTagLogLevel(logger.DefaultTag, "info")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+
+	assert.Equal(t, "info", findLevel(t, "unset").LogLevel, "default not applied")
+	assert.Equal(t, "debug", findLevel(t, "main").LogLevel, "explicit level changed")
+
+	// the global channel is not silenced by the default
+	err = logger.UpdateTagLogLevel(logger.DefaultTag, "off")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+	assert.Equal(t, "critical", findLevel(t, "PANIC").LogLevel, "global channel level changed")
+}
+
+func TestChannelRegistry(t *testing.T) {
+	setup(t)
+	defer teardown()
+	defer logger.Finalise()
+
+	first := logger.New("shared")
+	second := logger.New("shared")
+	assert.Same(t, first, second, "channel not shared")
+
+	// the global channel is not reopened by Initialise
+	assert.Equal(t, 1, findLevel(t, "PANIC").Count, "wrong global channel count")
+
+	l := findLevel(t, "shared")
+	assert.Equal(t, 2, l.Count, "wrong channel count")
+
+	first.Close()
+	l = findLevel(t, "shared")
+	assert.Equal(t, 1, l.Count, "wrong channel count")
+
+	// closing is counted, so extra closes are harmless
+	second.Close()
+	second.Close()
+
+	bs, err := logger.ListLevels()
+	assert.Nil(t, err, "wrong ListLevels")
+
+	var s logger.LogLevels
+	err = json.Unmarshal(bs, &s)
+	assert.Nil(t, err, "wrong bytes unmarshal")
+	for _, l := range s.Levels {
+		assert.NotEqual(t, "shared", l.Tag, "closed channel still listed")
+	}
+
+	// a closed channel can still be used
+	second.Warn("This should log")
+}
+
+func TestCustomLevels(t *testing.T) {
+	// may already be registered if the test is repeated
+	_ = level.Register(level.Definition{Name: "notice", Number: level.InfoLevel + 5})
+	_ = level.Register(level.Definition{Name: "audit", Number: level.CriticalLevel + 5, Unfiltered: true})
+
+	noticeLevel := level.ValidLevels["notice"]
+	auditLevel := level.ValidLevels["audit"]
+
+	setup(t)
+	defer teardown()
+
+	err := logger.UpdateTagLogLevel("custom", "notice")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+
+	customLog := logger.New("custom")
+	customLog.Info("This should not log")
+	customLog.Log(noticeLevel, "This should log")
+	customLog.Logf(level.WarnLevel, "This should %s", "log")
+	customLog.Log(level.OffLevel, "This should not log")
+	customLog.Log(12345, "This should not log")
+
+	err = logger.UpdateTagLogLevel("custom", "off")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+	customLog.Logc(auditLevel, func() string {
+		return "This should log"
+	})
+
+	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
+2014-08-12 10:44:35 [NOTICE] custom: This should log
+2014-08-12 10:44:35 [WARN] custom: This should log
+2014-08-12 10:44:35 [AUDIT] custom: This should log
+2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
+`)
+}
+
+func TestClock(t *testing.T) {
+	fixed := time.Date(2014, 8, 12, 10, 44, 35, 123456789, time.FixedZone("UTC+8", 8*60*60))
+	setup(t, func(c *logger.Configuration) {
+		c.Timestamp = logger.TimestampRFC3339Milli
+		c.UTC = true
+		c.Clock = func() time.Time {
+			return fixed
+		}
+	})
+	defer teardown()
+
+	mainLog := logger.New("main")
+	mainLog.Info("This should log")
+	logger.Finalise()
+
+	bs, err := os.ReadFile(path.Join(logDirectory, logFileName))
+	assert.Nil(t, err, "wrong ReadFile")
+	assert.Equal(t, `2014-08-12T02:44:35.123Z [WARN] LOGGER: ===== Logging system started =====
+2014-08-12T02:44:35.123Z [INFO] main: This should log
+2014-08-12T02:44:35.123Z [WARN] LOGGER: ===== Logging system stopped =====
+`, string(bs), "wrong log file")
+}
+
+func TestTimestampLayouts(t *testing.T) {
+	valid := []string{
+		"",
+		logger.TimestampDefault,
+		logger.TimestampRFC3339Milli,
+		logger.TimestampUnixMilli,
+		time.RFC1123Z,
+		"2006-01-02 03:04:05.000 PM",
+	}
+	for _, timestamp := range valid {
+		c := testConfiguration(func(c *logger.Configuration) {
+			c.Timestamp = timestamp
+		})
+		assert.Nil(t, c.Validate(), "valid timestamp rejected: %q", timestamp)
+	}
+
+	invalid := []string{
+		"rfc3339-milli",
+		"iso8601",
+		"15:04:05",
+		"2006-01-02 03:04:05",
+	}
+	for _, timestamp := range invalid {
+		c := testConfiguration(func(c *logger.Configuration) {
+			c.Timestamp = timestamp
+		})
+		assert.Equal(t, logger.ValidationErrors{
+			&logger.FieldError{Field: "Timestamp", Value: timestamp, Reason: "is not a timestamp name or layout"},
+		}, c.Validate(), "invalid timestamp accepted: %q", timestamp)
+	}
+}
+
+func TestClockOncePerRecord(t *testing.T) {
+	// each reading is a second later, as a replay tool might produce
+	start := time.Date(2014, 8, 12, 10, 44, 35, 0, time.UTC)
+	var readings atomic.Int64
+	setup(t, func(c *logger.Configuration) {
+		c.Console = true
+		c.Chain = true
+		c.UTC = true
+		c.Clock = func() time.Time {
+			return start.Add(time.Duration(readings.Add(1)) * time.Second)
+		}
+	})
+	defer teardown()
+
+	mainLog := logger.New("main")
+	mainLog.Info("first")
+	mainLog.Info("second")
+	logger.Finalise()
+
+	// one reading per record, shared by the file, chain and console
+	assert.Equal(t, int64(4), readings.Load(), "wrong number of clock readings")
+
+	bs, err := os.ReadFile(path.Join(logDirectory, logFileName))
+	assert.Nil(t, err, "wrong ReadFile")
+	lines := strings.Split(strings.TrimSuffix(string(bs), "\n"), "\n")
+	expected := []string{
+		"2014-08-12 10:44:36 [WARN] LOGGER: ===== Logging system started =====",
+		"2014-08-12 10:44:37 [INFO] main: first",
+		"2014-08-12 10:44:38 [INFO] main: second",
+		"2014-08-12 10:44:39 [WARN] LOGGER: ===== Logging system stopped =====",
+	}
+	if assert.Equal(t, len(expected), len(lines), "wrong line count") {
+		for i, line := range lines {
+			assert.True(t, strings.HasPrefix(line, expected[i]+" #"), "wrong line: %q", line)
+		}
+	}
+}
+
+// configuration modifier for a hash chained log
+func chained(c *logger.Configuration) {
+	c.Chain = true
+}
+
+func TestChain(t *testing.T) {
+	setup(t, chained)
+	defer teardown()
+
+	mainLog := logger.New("main")
+	for i := 0; i < 500; i += 1 {
+		mainLog.Infof("record number: %d with some padding to fill the file", i)
+	}
+	mainLog.Info("a multi-line\nmessage")
+	logger.Finalise()
+
+	files, err := logger.RotatedFiles(logDirectory, logFileName)
+	assert.Nil(t, err, "wrong RotatedFiles")
+	assert.Greater(t, len(files), 1, "log was not rotated")
+	assert.Equal(t, path.Join(logDirectory, logFileName), files[len(files)-1], "current file not last")
+
+	err = logger.VerifyChain(logDirectory, logFileName)
+	assert.Nil(t, err, "wrong VerifyChain")
+
+	// the chain continues after a restart
+	startLogging(t, chained)
+	mainLog = logger.New("main")
+	mainLog.Info("after restart")
+	logger.Finalise()
+
+	err = logger.VerifyChain(logDirectory, logFileName)
+	assert.Nil(t, err, "chain broken by restart")
+
+	// tamper with an archived record
+	bs, err := os.ReadFile(files[0])
+	assert.Nil(t, err, "wrong ReadFile")
+	lines := strings.Split(string(bs), "\n")
+	lines[5] = strings.Replace(lines[5], "record number", "record NUMBER", 1)
+	err = os.WriteFile(files[0], []byte(strings.Join(lines, "\n")), 0600)
+	assert.Nil(t, err, "wrong WriteFile")
+
+	err = logger.VerifyChain(logDirectory, logFileName)
+	if assert.NotNil(t, err, "tampering not detected") {
+		ce, ok := err.(*logger.ChainError)
+		if assert.True(t, ok, "wrong error type") {
+			assert.Equal(t, files[0], ce.File, "wrong file")
+			assert.Equal(t, 6, ce.Line, "wrong line")
+		}
+	}
+
+	// restore, then truncate the current file
+	err = os.WriteFile(files[0], bs, 0600)
+	assert.Nil(t, err, "wrong WriteFile")
+
+	// an archive compressed after rotation is still verified
+	compressed := compressFile(t, files[0])
+	files, err = logger.RotatedFiles(logDirectory, logFileName)
+	assert.Nil(t, err, "wrong RotatedFiles")
+	assert.Equal(t, compressed, files[0], "compressed archive not listed")
+	err = logger.VerifyChain(logDirectory, logFileName)
+	assert.Nil(t, err, "chain broken by compression")
+
+	current := path.Join(logDirectory, logFileName)
+	bs, err = os.ReadFile(current)
+	assert.Nil(t, err, "wrong ReadFile")
+	lines = strings.Split(strings.TrimSuffix(string(bs), "\n"), "\n")
+	err = os.WriteFile(current, []byte(strings.Join(lines[:len(lines)-1], "\n")+"\n"), 0600)
+	assert.Nil(t, err, "wrong WriteFile")
+
+	err = logger.VerifyChain(logDirectory, logFileName)
+	assert.NotNil(t, err, "truncation not detected")
+}
+
+// replace a file with a gzip compressed copy as logrotate would
+func compressFile(t *testing.T, name string) string {
+	bs, err := os.ReadFile(name)
+	assert.Nil(t, err, "wrong ReadFile")
+
+	f, err := os.Create(name + ".gz")
+	assert.Nil(t, err, "wrong Create")
+	z := gzip.NewWriter(f)
+	z.Write(bs)
+	z.Close()
+	f.Close()
+
+	os.Remove(name)
+	return name + ".gz"
+}
+
+func TestSignedSegments(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.Nil(t, err, "wrong GenerateKey")
+
+	setup(t, func(c *logger.Configuration) {
+		c.Signer = privateKey
+	})
+	defer teardown()
+
+	mainLog := logger.New("main")
+	for i := 0; i < 500; i += 1 {
+		mainLog.Infof("record number: %d with some padding to fill the file", i)
+	}
+	logger.Finalise()
+
+	files, err := logger.RotatedFiles(logDirectory, logFileName)
+	assert.Nil(t, err, "wrong RotatedFiles")
+	assert.Greater(t, len(files), 1, "log was not rotated")
+
+	err = logger.VerifySignatures(logDirectory, logFileName, publicKey)
+	assert.Nil(t, err, "wrong VerifySignatures")
+
+	// compression keeps the signature of the original
+	original, err := os.ReadFile(files[0])
+	assert.Nil(t, err, "wrong ReadFile")
+	compressed := compressFile(t, files[0])
+	err = logger.VerifySignatures(logDirectory, logFileName, publicKey)
+	assert.Nil(t, err, "compressed archive not verified")
+	os.Remove(compressed)
+	os.WriteFile(files[0], original, 0600)
+
+	otherKey, _, err := ed25519.GenerateKey(nil)
+	assert.Nil(t, err, "wrong GenerateKey")
+	err = logger.VerifySignatures(logDirectory, logFileName, otherKey)
+	assert.NotNil(t, err, "wrong key not detected")
+
+	// tamper with an archive
+	f, err := os.OpenFile(files[0], os.O_APPEND|os.O_WRONLY, 0600)
+	assert.Nil(t, err, "wrong OpenFile")
+	f.WriteString("extra line\n")
+	f.Close()
+
+	err = logger.VerifySignatures(logDirectory, logFileName, publicKey)
+	if assert.NotNil(t, err, "tampering not detected") {
+		se, ok := err.(*logger.SignatureError)
+		if assert.True(t, ok, "wrong error type") {
+			assert.Equal(t, files[0], se.File, "wrong file")
+		}
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	setup(t, func(c *logger.Configuration) {
+		c.Redact = logger.RedactionConfiguration{
+			Patterns: []string{`secret-[0-9]+`},
+			Fields:   []string{"token"},
+			Tags: map[string]logger.RedactionRules{
+				"aux":        {Fields: []string{"password"}},
+				"rpc":        {Fields: []string{"password"}},
+				"rpc.auth.*": {Fields: []string{"pin"}},
+			},
+		}
+	})
+	defer teardown()
+
+	mainLog := logger.New("main")
+	auxLog := logger.New("aux")
+	rpcLog := logger.New("rpc")
+	authLog := logger.New("rpc.auth")
+	sessionLog := logger.New("rpc.auth.session")
+	otherLog := logger.New("rpcx")
+
+	mainLog.Infof("value: %s token=%s user=%q", "secret-1234", "abcdef", "me")
+	mainLog.Infof("token=%q password=%s", "quoted value", "visible")
+	mainLog.Infof("seed: %x %v", logger.Redacted{[]byte{1, 2, 3}}, logger.Redacted{"text"})
+	auxLog.Warnf("password=%s", "hidden")
+
+	// descendants get the rules of all their ancestors
+	rpcLog.Errorf("login password=%s pin=%s", "hunter2", "1234")
+	authLog.Errorf("login password=%s pin=%s", "hunter2", "1234")
+	sessionLog.Errorf("login password=%s pin=%s", "hunter2", "1234")
+	otherLog.Errorf("login password=%s", "visible")
+
+	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
+2014-08-12 10:44:35 [INFO] main: value: *** token=*** user="me"
+2014-08-12 10:44:35 [INFO] main: token=*** password=visible
+2014-08-12 10:44:35 [INFO] main: seed: *** ***
+2014-08-12 10:44:35 [WARN] aux: password=***
+2014-08-12 10:44:35 [ERROR] rpc: login password=*** pin=1234
+2014-08-12 10:44:35 [ERROR] rpc.auth: login password=*** pin=1234
+2014-08-12 10:44:35 [ERROR] rpc.auth.session: login password=*** pin=***
+2014-08-12 10:44:35 [ERROR] rpcx: login password=visible
+2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
+`)
+}
+
+func TestRedactionInvalidPattern(t *testing.T) {
+	defer teardown()
+
+	err := initialiseError(func(c *logger.Configuration) {
+		c.Redact = logger.RedactionConfiguration{
+			Patterns: []string{`(`},
+		}
+	})
+	assert.NotNil(t, err, "invalid pattern accepted")
+}
+
+func TestSampling(t *testing.T) {
+	setup(t, func(c *logger.Configuration) {
+		c.Sampling = map[string]logger.Sampling{
+			"main": {
+				Type:         logger.SampleFirst,
+				First:        2,
+				Thereafter:   3,
+				Interval:     "1h",
+				BypassErrors: true,
+			},
+			"aux": {
+				Type:        logger.SampleRandom,
+				Probability: 0,
+			},
+		}
+	})
+	defer teardown()
+
+	mainLog := logger.New("main")
+	auxLog := logger.New("aux")
+	for i := 1; i <= 8; i += 1 {
+		mainLog.Infof("record %d", i)
+	}
+	mainLog.Error("This should log")
+	auxLog.Warn("This should not log")
+
+	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
+2014-08-12 10:44:35 [INFO] main: record 1
+2014-08-12 10:44:35 [INFO] main: record 2
+2014-08-12 10:44:35 [INFO] main: record 5
+2014-08-12 10:44:35 [INFO] main: record 8
+2014-08-12 10:44:35 [ERROR] main: This should log
+2014-08-12 10:44:35 [WARN] aux: sampling suppressed 1 records
+2014-08-12 10:44:35 [WARN] main: sampling suppressed 4 records
+2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
+`)
+}
+
+func TestSamplingInvalid(t *testing.T) {
+	defer teardown()
+
+	err := initialiseError(func(c *logger.Configuration) {
+		c.Sampling = map[string]logger.Sampling{
+			"main": {Type: logger.SampleBucket},
+		}
+	})
+	assert.NotNil(t, err, "invalid sampling accepted")
+}
+
+func TestDeduplicate(t *testing.T) {
+	setup(t, func(c *logger.Configuration) {
+		c.Deduplicate = "1h"
+	})
+	defer teardown()
+
+	mainLog := logger.New("main")
+	auxLog := logger.New("aux")
+	for i := 0; i < 5; i += 1 {
+		mainLog.Warn("peer misbehaving")
+	}
+	mainLog.Error("peer misbehaving")
+	mainLog.Error("different")
+	for i := 0; i < 3; i += 1 {
+		auxLog.Warn("repeat until flush")
+	}
+	logger.Flush()
+	auxLog.Warn("repeat until flush")
+
+	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
+2014-08-12 10:44:35 [WARN] main: peer misbehaving
+2014-08-12 10:44:35 [WARN] main: last message repeated 4 times
+2014-08-12 10:44:35 [ERROR] main: peer misbehaving
+2014-08-12 10:44:35 [ERROR] main: different
+2014-08-12 10:44:35 [WARN] aux: repeat until flush
+2014-08-12 10:44:35 [WARN] aux: last message repeated 2 times
+2014-08-12 10:44:35 [WARN] aux: repeat until flush
+2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
+`)
+}
+
+func TestDeduplicateExpiry(t *testing.T) {
+	setup(t, func(c *logger.Configuration) {
+		c.Deduplicate = "50ms"
+	})
+	defer teardown()
+
+	mainLog := logger.New("main")
+	auxLog := logger.New("aux")
+	for i := 0; i < 3; i += 1 {
+		mainLog.Warn("peer misbehaving")
+	}
+
+	// the summary is written once the window has passed without
+	// waiting for another message or a flush
+	time.Sleep(250 * time.Millisecond)
+	auxLog.Warn("later")
+	mainLog.Warn("peer misbehaving")
+
+	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
+2014-08-12 10:44:35 [WARN] main: peer misbehaving
+2014-08-12 10:44:35 [WARN] main: last message repeated 2 times
+2014-08-12 10:44:35 [WARN] aux: later
+2014-08-12 10:44:35 [WARN] main: peer misbehaving
+2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
+`)
+}
+
+func TestPrettyConsole(t *testing.T) {
+	assert.Equal(t, "   +1.500s WARN     main     peer lost id=7 reason=\"timed out\"",
+		logger.PrettyWarning(`main: peer lost id=7 reason="timed out"`, false), "wrong plain text")
+
+	assert.Equal(t, "   +1.500s \x1b[33mWARN    \x1b[0m main     peer lost \x1b[2mid=\x1b[0m7",
+		logger.PrettyWarning("main: peer lost id=7", true), "wrong coloured text")
+
+	// long tags widen the column
+	assert.Equal(t, "   +1.500s WARN     a-long-tag-name message",
+		logger.PrettyWarning("a-long-tag-name: message", false), "wrong long tag")
+}
+
+func TestConsoleFormat(t *testing.T) {
+	cases := []struct {
+		name     string
+		style    string
+		terminal bool
+		noColour string // "-" to unset
+		id       string
+		colour   bool
+	}{
+		{"plain", logger.ConsolePlain, true, "-", "all", false},
+		{"pretty without terminal", logger.ConsolePretty, false, "-", "all", false},
+		{"pretty", logger.ConsolePretty, true, "-", "console", true},
+		{"NO_COLOR set", logger.ConsolePretty, true, "1", "console", false},
+		{"NO_COLOR empty", logger.ConsolePretty, true, "", "console", true},
+	}
+	for _, c := range cases {
+		t.Setenv("NO_COLOR", c.noColour)
+		if "-" == c.noColour {
+			os.Unsetenv("NO_COLOR")
+		}
+		id, colour, err := logger.ConsoleFormat(c.style, c.terminal)
+		assert.Nil(t, err, "consoleFormat error: %s", c.name)
+		assert.Equal(t, c.id, id, "wrong format: %s", c.name)
+		if "console" == c.id {
+			assert.Equal(t, c.colour, colour, "wrong colour: %s", c.name)
+		}
+	}
+
+	_, _, err := logger.ConsoleFormat("fancy", true)
+	assert.NotNil(t, err, "invalid style accepted")
+}
+
+func TestConsoleStyle(t *testing.T) {
+	defer teardown()
+
+	err := initialiseError(func(c *logger.Configuration) {
+		c.Console = true
+		c.ConsoleStyle = "fancy"
+	})
+	assert.NotNil(t, err, "invalid console style accepted")
+
+	// the file is unchanged by a pretty console
+	setup(t, func(c *logger.Configuration) {
+		c.Console = true
+		c.ConsoleStyle = logger.ConsolePretty
+	})
+
+	mainLog := logger.New("main")
+	mainLog.Info("This should log")
+
+	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
+2014-08-12 10:44:35 [INFO] main: This should log
+2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
+`)
+}
+
+func TestOutputLevels(t *testing.T) {
+	setup(t, func(c *logger.Configuration) {
+		c.Console = true
+		c.OutputLevels = map[string]string{
+			logger.OutputFile:    "warn",
+			logger.OutputConsole: "off",
+		}
+	})
+	defer teardown()
+
+	bs, err := logger.ListLevels()
+	assert.Nil(t, err, "wrong ListLevels")
+	var s logger.LogLevels
+	err = json.Unmarshal(bs, &s)
+	assert.Nil(t, err, "wrong bytes unmarshal")
+	assert.Equal(t, []logger.OutputLevel{
+		{Output: logger.OutputConsole, LogLevel: "off"},
+		{Output: logger.OutputFile, LogLevel: "warn"},
+	}, s.Outputs, "wrong output levels")
+
+	mainLog := logger.New("main")
+	mainLog.Debug("This should not log")
+	mainLog.Info("This should not log")
+	mainLog.Warn("This should log")
+	mainLog.Error("This should log")
+
+	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
+2014-08-12 10:44:35 [WARN] main: This should log
+2014-08-12 10:44:35 [ERROR] main: This should log
+2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
+`)
+}
+
+func TestOutputLevelsInvalid(t *testing.T) {
+	defer teardown()
+
+	invalid := []map[string]string{
+		{"syslog": "warn"},
+		{logger.OutputFile: "loud"},
+	}
+	for _, outputLevels := range invalid {
+		err := initialiseError(func(c *logger.Configuration) {
+			c.OutputLevels = outputLevels
+		})
+		assert.NotNil(t, err, "invalid output levels accepted: %v", outputLevels)
+	}
+
+	// no output left: reported by Validate as well as by Initialise
+	for _, console := range []bool{false, true} {
+		noOutput := func(c *logger.Configuration) {
+			c.Console = console
+			c.OutputLevels = map[string]string{
+				logger.OutputFile:    "off",
+				logger.OutputConsole: "off",
+			}
+		}
+		err := testConfiguration(noOutput).Validate()
+		var errs logger.ValidationErrors
+		if assert.ErrorAs(t, err, &errs, "no output accepted, console: %t", console) {
+			assert.Equal(t, &logger.FieldError{Field: "OutputLevels[file]", Value: "off", Reason: "leaves no output enabled"}, errs[0], "wrong error")
+		}
+		assert.Equal(t, err, initialiseError(noOutput), "wrong initialise error, console: %t", console)
+	}
+}
+
+// a function depending only on the Logger interface
+func logThroughInterface(log logger.Logger) {
+	log.Info("hello")
+	log.Warnf("value=%d", 42)
+	log.Debugc(func() string {
+		return "closure"
+	})
+	log.Log(level.ErrorLevel, "any level")
+}
+
+func TestLoggerInterface(t *testing.T) {
+	// must not panic or call the closure
+	logThroughInterface(logger.NopLogger{})
+
+	var mock logger.MockLogger
+	logThroughInterface(&mock)
+
+	assert.Equal(t, []logger.MockRecord{
+		{Method: "Info", Level: level.InfoLevel, Message: "hello"},
+		{Method: "Warnf", Level: level.WarnLevel, Message: "value=42"},
+		{Method: "Debugc", Level: level.DebugLevel, Message: "closure"},
+		{Method: "Log", Level: level.ErrorLevel, Message: "any level"},
+	}, mock.Records(), "wrong mock records")
+
+	mock.Reset()
+	assert.Equal(t, 0, len(mock.Records()), "records not reset")
+}
+
+func TestDiscard(t *testing.T) {
+	assert.NotPanics(t, func() {
+		logger.Discard.Critical("discarded")
+		logger.Discard.Errorf("discarded %d", 1)
+		logger.Discard.Infoc(func() string {
+			t.Error("closure called")
+			return ""
+		})
+		logger.Discard.Log(level.CriticalLevel, "discarded")
+		logger.Discard.Flush()
+		logger.Discard.Close()
+	}, "discard channel panicked")
+}
+
+func TestFallback(t *testing.T) {
+	defer logger.SetFallback(logger.FallbackPanic)
+
+	var l *logger.L
+
+	assert.PanicsWithValue(t, "logger is not initialised", func() {
+		l.Error("nil channel")
+	}, "nil channel did not panic")
+
+	logger.SetFallback(logger.FallbackDrop)
+	assert.NotPanics(t, func() {
+		l.Error("dropped")
+		l.Logf(level.ErrorLevel, "dropped %d", 1)
+	}, "dropped message panicked")
+
+	logger.SetFallback(logger.FallbackStderr)
+	assert.NotPanics(t, func() {
+		l.Critical("fallback to stderr")
+	}, "stderr message panicked")
+}
+
+func TestFallbackBeforeInitialise(t *testing.T) {
+	defer logger.SetFallback(logger.FallbackPanic)
+	logger.SetFallback(logger.FallbackDrop)
+
+	r, w, err := os.Pipe()
+	if !assert.Nil(t, err, "pipe error") {
+		return
+	}
+	defer r.Close()
+	stdout := os.Stdout
+	os.Stdout = w
+
+	// a channel from New is not affected by the fallback
+	early := logger.New("early")
+	defer early.Close()
+	early.Critical("not dropped")
+	early.Flush()
+	os.Stdout = stdout
+	w.Close()
+
+	bs, err := io.ReadAll(r)
+	assert.Nil(t, err, "read error")
+	assert.Contains(t, string(bs), "[CRITICAL] early: not dropped", "message not on standard output")
+}
+
+func TestLazyBinding(t *testing.T) {
+	// created before Initialise, e.g. as a package level variable
+	early := logger.New("aux")
+
+	setup(t)
+	defer teardown()
+
+	early.Info("This should not log")
+	early.Warn("This should log")
+
+	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
+2014-08-12 10:44:35 [WARN] aux: This should log
+2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
+`)
+
+	// still usable after Finalise and a new Initialise
+	setup(t)
+
+	early.Warn("This should log again")
+
+	checkfile(t, `2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system started =====
+2014-08-12 10:44:35 [WARN] aux: This should log again
+2014-08-12 10:44:35 [WARN] LOGGER: ===== Logging system stopped =====
+`)
+}
+
+func TestRebindOnInitialise(t *testing.T) {
+	// created before Initialise, but not yet used
+	stale := logger.New("stale")
+	defer stale.Close()
+	err := logger.UpdateTagLogLevel("stale", "error")
+	assert.Nil(t, err, "wrong UpdateTagLogLevel")
+
+	setup(t, func(c *logger.Configuration) {
+		c.Levels = map[string]string{"stale": "debug"}
+	})
+	defer teardown()
+
+	assert.Equal(t, "debug", findLevel(t, "stale").LogLevel, "level not applied by Initialise")
+
+	err = logger.OverrideTagLogLevel("stale", "trace", time.Hour)
+	assert.Nil(t, err, "wrong OverrideTagLogLevel")
+	assert.Equal(t, "debug", findLevel(t, "stale").Override.Original, "wrong original level")
+
+	// Finalise ends the override but keeps the channel registered
+	logger.Finalise()
+	assert.Equal(t, "debug", findLevel(t, "stale").LogLevel, "override not ended by Finalise")
+
+	other := logger.New("stale")
+	assert.Same(t, stale, other, "channel not shared after Finalise")
+	other.Close()
+
+	stale.Warn("This should log")
+
+	bs, err := logger.ListLevels()
+	assert.Nil(t, err, "wrong ListLevels")
+	var s logger.LogLevels
+	err = json.Unmarshal(bs, &s)
+	assert.Nil(t, err, "wrong bytes unmarshal")
+	n := 0
+	for _, l := range s.Levels {
+		if "stale" == l.Tag {
+			n += 1
+		}
+	}
+	assert.Equal(t, 1, n, "channel listed more than once")
+}
+
+func TestConcurrentRebinding(t *testing.T) {
+	removeLogFiles()
+	defer teardown()
+
+	l := logger.New("concurrent")
+	defer l.Close()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i += 1 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; ; n += 1 {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				l.Info("filtered")
+				if 0 == n%10000 {
+					l.Errorf("message: %d", n)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 5; i += 1 {
+		setup(t)
+		time.Sleep(5 * time.Millisecond)
+		logger.Finalise()
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestNewBeforeFailedInitialise(t *testing.T) {
+	removeLogFiles()
+	defer teardown()
+
+	// directory does not exist
+	err := logger.Initialise(testConfiguration())
+	assert.NotNil(t, err, "initialise without directory succeeded")
+
+	assert.NotPanics(t, func() {
+		l := logger.New("aux")
+		l.Critical("to standard output")
+	}, "channel after failed initialise panicked")
+	logger.Finalise()
+}
+
+func TestDirectoryErrors(t *testing.T) {
+	base := t.TempDir()
+	notDirectory := path.Join(base, "file")
+	os.WriteFile(notDirectory, []byte("x"), 0600)
+	readOnly := path.Join(base, "readonly")
+	os.Mkdir(readOnly, 0500)
+
+	cases := []struct {
+		directory string
+		expected  error
+	}{
+		{path.Join(base, "missing"), logger.ErrDirectoryMissing},
+		{notDirectory, logger.ErrNotDirectory},
+		{readOnly, logger.ErrNotWritable},
+	}
+	for _, c := range cases {
+		if logger.ErrNotWritable == c.expected && 0 == os.Geteuid() {
+			// permissions do not restrict root
+			continue
+		}
+		err := logger.Initialise(logger.Configuration{
+			Directory: c.directory,
+			File:      logFileName,
+			Size:      logSizeOfFiles,
+			Count:     logNumberOfFiles,
+		})
+		assert.ErrorIs(t, err, c.expected, "wrong error for: %s", c.directory)
+
+		var directoryError *logger.DirectoryError
+		if assert.ErrorAs(t, err, &directoryError, "not a directory error") {
+			assert.Equal(t, c.directory, directoryError.Directory, "wrong directory")
+		}
+		logger.Finalise()
+	}
+}
+
+func TestCreateDirectory(t *testing.T) {
+	directory := path.Join(t.TempDir(), "a", "b")
+	c := logger.Configuration{
+		Directory:       directory,
+		File:            logFileName,
+		Size:            logSizeOfFiles,
+		Count:           logNumberOfFiles,
+		CreateDirectory: true,
+		DirectoryMode:   "0770", // wider than the usual umask allows
+		FileMode:        "0600",
+	}
+	parent, err := os.Stat(path.Dir(path.Dir(directory)))
+	if !assert.Nil(t, err, "stat error") {
+		return
+	}
+	err = logger.Initialise(c)
+	if !assert.Nil(t, err, "initialise error") {
+		return
+	}
+	// enough to rotate the file
+	mainLog := logger.New("main")
+	for i := 0; i < 500; i += 1 {
+		mainLog.Errorf("This should log %d %s", i, strings.Repeat("x", 80))
+	}
+	logger.Finalise()
+
+	for _, d := range []string{directory, path.Dir(directory)} {
+		info, err := os.Stat(d)
+		if assert.Nil(t, err, "directory not created") {
+			assert.Equal(t, os.FileMode(0770), info.Mode().Perm(), "wrong mode for: %s", d)
+		}
+	}
+	info, err := os.Stat(path.Dir(path.Dir(directory)))
+	if assert.Nil(t, err, "stat error") {
+		assert.Equal(t, parent.Mode(), info.Mode(), "existing directory changed")
+	}
+	files, err := logger.RotatedFiles(directory, logFileName)
+	assert.Nil(t, err, "rotated files error")
+	assert.True(t, len(files) > 1, "log file not rotated")
+	for _, f := range files {
+		info, err = os.Stat(f)
+		if assert.Nil(t, err, "log file error") {
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "wrong mode for: %s", f)
+		}
+	}
+
+	// no probe files remain
+	entries, _ := os.ReadDir(directory)
+	assert.Equal(t, len(files), len(entries), "unexpected files: %v", entries)
+}
+
+func TestPermissionsInvalid(t *testing.T) {
+	invalid := []logger.Configuration{
+		{DirectoryMode: "rwx"},
+		{FileMode: "01777"},
+		{Owner: "no-such-user-for-logger-test"},
+		{Group: "no-such-group-for-logger-test"},
+	}
+	for _, c := range invalid {
+		c.Directory = t.TempDir()
+		c.File = logFileName
+		c.Size = logSizeOfFiles
+		c.Count = logNumberOfFiles
+		err := logger.Initialise(c)
+		assert.NotNil(t, err, "invalid permissions accepted: %+v", c)
+		logger.Finalise()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := logger.Configuration{
+		File:  "a/b.log",
+		Size:  100,
+		Count: logNumberOfFiles,
+		Levels: map[string]string{
+			"main": "debug",
+			"aux":  "loud",
+		},
+		ConsoleStyle: "fancy",
+		Timestamp:    "rfc3339-milli",
+		Deduplicate:  "-1s",
+		OutputLevels: map[string]string{
+			logger.OutputConsole: "verbose",
+		},
+		Sampling: map[string]logger.Sampling{
+			"peer": {Type: logger.SampleRandom, Probability: 2},
+		},
+	}
+
+	err := c.Validate()
+	var errs logger.ValidationErrors
+	if !assert.ErrorAs(t, err, &errs, "not validation errors") {
+		return
+	}
+
+	fields := []string{}
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{
+		"Directory",
+		"File",
+		"Size",
+		"Levels[aux]",
+		"ConsoleStyle",
+		"Timestamp",
+		"Sampling[peer].Probability",
+		"Deduplicate",
+		"OutputLevels[console]",
+	}, fields, "wrong fields")
+
+	assert.Equal(t, &logger.FieldError{Field: "Levels[aux]", Value: "loud", Reason: "is not a level"}, errs[3], "wrong level error")
+	assert.Equal(t, `Size: "100" cannot be less than: 20000`, errs[2].Error(), "wrong message")
+
+	c = logger.Configuration{
+		Directory: logDirectory,
+		File:      logFileName,
+		Size:      logSizeOfFiles,
+		Count:     logNumberOfFiles,
+	}
+	assert.Nil(t, c.Validate(), "valid configuration rejected")
+
+	// a receiver replaces the log file settings
+	c = logger.Configuration{
+		Receiver: loggertest.New(t),
+		Chain:    true,
+	}
+	err = c.Validate()
+	if assert.ErrorAs(t, err, &errs, "not validation errors") && assert.Equal(t, 1, len(errs), "wrong error count") {
+		assert.Equal(t, "Chain", errs[0].Field, "wrong field")
+	}
+}
+
+func TestStrictLevels(t *testing.T) {
+	defer teardown()
+
+	unknownLevel := func(c *logger.Configuration) {
+		c.Levels = map[string]string{"aux": "loud"}
+	}
+	err := initialiseError(unknownLevel, func(c *logger.Configuration) {
+		c.Strict = true
+	})
+	var errs logger.ValidationErrors
+	if assert.ErrorAs(t, err, &errs, "unknown level accepted in strict mode") {
+		assert.Equal(t, "Levels[aux]", errs[0].Field, "wrong field")
+	}
+
+	// unknown levels are ignored by default
+	setup(t, unknownLevel)
+	logger.Finalise()
+}
+
+func TestWithEnvironment(t *testing.T) {
+	file := logger.Configuration{
+		Directory: "/from/file",
+		File:      logFileName,
+		Size:      logSizeOfFiles,
+		Count:     logNumberOfFiles,
+		Levels: map[string]string{
+			"main": "info",
+			"aux":  "warn",
+		},
+	}
+
+	t.Setenv("APP_LOG_DIRECTORY", "/from/env")
+	t.Setenv("APP_LOG_SIZE", "50000")
+	t.Setenv("APP_LOG_CONSOLE", "true")
+	t.Setenv("APP_LOG_LEVELS", "main=debug, peer=trace")
+	t.Setenv("APP_LOG_SAMPLING", `{"peer":{"type":"random","probability":0.5}}`)
+	t.Setenv("APP_LOG_REDACT", `{"fields":["token"]}`)
+
+	c, err := file.WithEnvironment("APP_LOG")
+	if !assert.Nil(t, err, "environment error") {
+		return
+	}
+	assert.Equal(t, "/from/env", c.Directory, "wrong directory")
+	assert.Equal(t, logFileName, c.File, "file setting lost")
+	assert.Equal(t, 50000, c.Size, "wrong size")
+	assert.Equal(t, logNumberOfFiles, c.Count, "count setting lost")
+	assert.True(t, c.Console, "wrong console")
+	assert.Equal(t, map[string]string{
+		"main": "debug",
+		"aux":  "warn",
+		"peer": "trace",
+	}, c.Levels, "wrong merged levels")
+	assert.Equal(t, logger.Sampling{Type: logger.SampleRandom, Probability: 0.5}, c.Sampling["peer"], "wrong sampling")
+	assert.Equal(t, []string{"token"}, c.Redact.Fields, "wrong redact fields")
+
+	// the original is unchanged
+	assert.Equal(t, "info", file.Levels["main"], "file configuration modified")
+	_, ok := file.Levels["peer"]
+	assert.False(t, ok, "file configuration modified")
+}
+
+func TestWithEnvironmentInvalid(t *testing.T) {
+	t.Setenv("APP_LOG_SIZE", "big")
+	t.Setenv("APP_LOG_UTC", "maybe")
+	t.Setenv("APP_LOG_LEVELS", "main")
+
+	_, err := logger.Configuration{}.WithEnvironment("APP_LOG_")
+	var errs logger.ValidationErrors
+	if !assert.ErrorAs(t, err, &errs, "not validation errors") {
+		return
+	}
+	fields := []string{}
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"APP_LOG_SIZE", "APP_LOG_LEVELS", "APP_LOG_UTC"}, fields, "wrong fields")
+}
+
+func TestLoadConfiguration(t *testing.T) {
+	documents := map[string]string{
+		logger.FormatYAML: `
+application: ignored
+logging:
+  directory: /var/lib/app/log
+  file: app.log
+  levels:
+    main: debug
+    p2p.*: trace
+  sampling:
+    peer:
+      type: random
+      probability: 0.25
+`,
+		logger.FormatTOML: `
+application = "ignored"
+[logging]
+directory = "/var/lib/app/log"
+file = "app.log"
+[logging.levels]
+main = "debug"
+"p2p.*" = "trace"
+[logging.sampling.peer]
+type = "random"
+probability = 0.25
+`,
+		logger.FormatJSON: `{
+  "directory": "/var/lib/app/log",
+  "file": "app.log",
+  "levels": {"main": "debug", "p2p.*": "trace"},
+  "sampling": {"peer": {"type": "random", "probability": 0.25}}
+}`,
+	}
+
+	expected := logger.Configuration{
+		Directory: "/var/lib/app/log",
+		File:      "app.log",
+		Size:      logger.DefaultSize,
+		Count:     logger.DefaultCount,
+		Levels: map[string]string{
+			logger.DefaultTag: logger.DefaultLevel,
+			"main":            "debug",
+			"p2p.*":           "trace",
+		},
+		ConsoleStyle: logger.ConsolePlain,
+		Timestamp:    logger.TimestampDefault,
+		Sampling: map[string]logger.Sampling{
+			"peer": {Type: logger.SampleRandom, Probability: 0.25},
+		},
+		SamplingSummary: "1m0s",
+	}
+
+	for format, document := range documents {
+		c, err := logger.LoadConfiguration([]byte(document), format)
+		if !assert.Nil(t, err, "%s: load error", format) {
+			continue
+		}
+		assert.Equal(t, expected, c, "%s: wrong configuration", format)
+		assert.Nil(t, c.Validate(), "%s: invalid configuration", format)
+
+		// the canonical form loads back to the same configuration
+		canonical, err := c.Canonical(format)
+		if !assert.Nil(t, err, "%s: canonical error", format) {
+			continue
+		}
+		reloaded, err := logger.LoadConfiguration(canonical, format)
+		assert.Nil(t, err, "%s: reload error", format)
+		assert.Equal(t, c, reloaded, "%s: round trip changed configuration", format)
+
+		again, _ := reloaded.Canonical(format)
+		assert.Equal(t, string(canonical), string(again), "%s: canonical form not stable", format)
+	}
+
+	_, err := logger.LoadConfiguration([]byte("{}"), "ini")
+	assert.NotNil(t, err, "unknown format accepted")
+}
+
+func TestLoadConfigurationFile(t *testing.T) {
+	name := path.Join(t.TempDir(), "app.yml")
+	os.WriteFile(name, []byte("logging:\n  directory: log\n  file: app.log\n  size: 50000\n"), 0600)
+
+	c, err := logger.LoadConfigurationFile(name)
+	if assert.Nil(t, err, "load error") {
+		assert.Equal(t, 50000, c.Size, "wrong size")
+		assert.Equal(t, logger.DefaultCount, c.Count, "wrong default count")
+	}
+}
@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// In-memory capture of log messages for unit tests
+//
+// Channels opened by Sink.Channel belong to a single test, so tests
+// using separate sinks can run in parallel.
+//
+// Channels created by logger.New, e.g. a package level
+// "var log = logger.New(...)", write to the global output which Capture
+// sends to a sink instead of a log file.  As the global output is
+// shared, tests using Capture must not run in parallel; concurrent
+// captures are serialised, each waiting for the previous test to end.
+//
+// example of use
+//
+//	func TestSomething(t *testing.T) {
+//	  t.Parallel()
+//	  sink := loggertest.New(t)
+//	  log := sink.Channel("main")
+//
+//	  doSomething(log)
+//
+//	  sink.AssertContains("started")
+//	  sink.AssertCount(level.ErrorLevel, 0)
+//	  sink.AssertNoneAbove(level.WarnLevel)
+//	}
+//
+//	func TestGlobal(t *testing.T) {
+//	  sink := loggertest.Capture(t, logger.Configuration{
+//	    Levels: map[string]string{logger.DefaultTag: "debug"},
+//	  })
+//
+//	  doSomethingLoggingWithNew()
+//
+//	  sink.AssertContains("started")
+//	}
+package loggertest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bitmark-inc/logger"
+	"github.com/bitmark-inc/logger/level"
+)
+
+// Record - a captured log message
+//
+// Fields holds any key=value items found in the message, values may be
+// quoted as by %q
+type Record struct {
+	logger.Record
+	Fields map[string]string
+}
+
+// Sink - holds the records captured during a test
+type Sink struct {
+	sync.Mutex
+	t       testing.TB
+	records []Record
+}
+
+// New - create an empty sink for a test
+func New(t testing.TB) *Sink {
+	return &Sink{t: t}
+}
+
+// serialises the use of the global output
+var captureLock sync.Mutex
+
+// Capture - initialise logging so that all channels write to a new sink
+// instead of a log file, logging is finalised when the test ends
+//
+// the configuration applies as for a log file, e.g. levels, redaction
+// and sampling, but Directory, File, Size and Count are not needed; the
+// test must not be parallel
+func Capture(t testing.TB, configuration logger.Configuration) *Sink {
+	t.Helper()
+
+	captureLock.Lock()
+	s := New(t)
+	configuration.Receiver = s
+	err := logger.Initialise(configuration)
+	if nil != err {
+		captureLock.Unlock()
+		t.Fatalf("loggertest: capture failed with error: %v", err)
+	}
+	t.Cleanup(func() {
+		logger.Finalise()
+		captureLock.Unlock()
+	})
+	return s
+}
+
+// Channel - open a logging channel that writes to the sink, all levels
+// are captured and the global configuration (levels, clock, redaction)
+// does not apply
+func (s *Sink) Channel(tag string) *logger.L {
+	s.t.Helper()
+
+	l, err := logger.NewDetached(tag, level.Trace, s)
+	if nil != err {
+		s.t.Fatalf("loggertest: channel: %q failed with error: %v", tag, err)
+	}
+	return l
+}
+
+// Receive - implements logger.Receiver
+func (s *Sink) Receive(record logger.Record) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.records = append(s.records, Record{
+		Record: record,
+		Fields: logger.Fields(record.Message),
+	})
+}
+
+// Records - all records captured so far in order
+func (s *Sink) Records() []Record {
+	s.Lock()
+	defer s.Unlock()
+
+	result := make([]Record, len(s.records))
+	copy(result, s.records)
+	return result
+}
+
+// Reset - discard all captured records
+func (s *Sink) Reset() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.records = nil
+}
+
+// Contains - check if any message contains the text
+func (s *Sink) Contains(text string) bool {
+	for _, r := range s.Records() {
+		if strings.Contains(r.Message, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count - number of records at a specific level
+func (s *Sink) Count(levelNumber int) int {
+	n := 0
+	for _, r := range s.Records() {
+		if int(r.Level) == levelNumber {
+			n += 1
+		}
+	}
+	return n
+}
+
+// AssertContains - fail the test if no message contains the text
+func (s *Sink) AssertContains(text string) bool {
+	s.t.Helper()
+
+	if !s.Contains(text) {
+		s.t.Errorf("loggertest: no message contains: %q", text)
+		return false
+	}
+	return true
+}
+
+// AssertCount - fail the test if the number of records at a level
+// differs from expected
+func (s *Sink) AssertCount(levelNumber int, expected int) bool {
+	s.t.Helper()
+
+	if n := s.Count(levelNumber); n != expected {
+		s.t.Errorf("loggertest: %s count: %d expected: %d", level.Level(levelNumber), n, expected)
+		return false
+	}
+	return true
+}
+
+// AssertNoneAbove - fail the test if any record is above a level
+func (s *Sink) AssertNoneAbove(levelNumber int) bool {
+	s.t.Helper()
+
+	ok := true
+	for _, r := range s.Records() {
+		if int(r.Level) > levelNumber {
+			s.t.Errorf("loggertest: unexpected %s: %s: %s", r.Level, r.Tag, r.Message)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// Fields - extract key=value items from a message
+func Fields(message string) map[string]string {
+	return logger.Fields(message)
+}
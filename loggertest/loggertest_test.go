@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package loggertest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bitmark-inc/logger"
+	"github.com/bitmark-inc/logger/level"
+	"github.com/bitmark-inc/logger/loggertest"
+)
+
+// records failures instead of failing the real test
+type fakeT struct {
+	testing.TB
+	failures int
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, arguments ...interface{}) {
+	f.failures += 1
+}
+
+func TestCapture(t *testing.T) {
+	t.Parallel()
+
+	sink := loggertest.New(t)
+	log := sink.Channel("main")
+
+	log.Debug("starting")
+	log.Infof("connected peer=%s count=%d note=%q", "10.0.0.1", 3, "two words")
+	log.Warnc(func() string { return "slow" })
+
+	records := sink.Records()
+	if !assert.Equal(t, 3, len(records), "wrong record count") {
+		return
+	}
+
+	assert.Equal(t, level.Level(level.DebugLevel), records[0].Level, "wrong level")
+	assert.Equal(t, "main", records[0].Tag, "wrong tag")
+	assert.Equal(t, "starting", records[0].Message, "wrong message")
+	assert.False(t, records[0].Time.IsZero(), "missing time")
+
+	assert.Equal(t, map[string]string{
+		"peer":  "10.0.0.1",
+		"count": "3",
+		"note":  "two words",
+	}, records[1].Fields, "wrong fields")
+
+	sink.AssertContains("slow")
+	sink.AssertCount(level.InfoLevel, 1)
+	sink.AssertNoneAbove(level.WarnLevel)
+
+	sink.Reset()
+	assert.Equal(t, 0, len(sink.Records()), "records not reset")
+}
+
+func TestParallelIsolation(t *testing.T) {
+	for i := 0; i < 4; i += 1 {
+		tag := fmt.Sprintf("worker-%d", i)
+		t.Run(tag, func(t *testing.T) {
+			t.Parallel()
+
+			sink := loggertest.New(t)
+			log := sink.Channel(tag)
+			for j := 0; j < 100; j += 1 {
+				log.Info(tag)
+			}
+
+			sink.AssertCount(level.InfoLevel, 100)
+			for _, r := range sink.Records() {
+				assert.Equal(t, tag, r.Tag, "record from another test")
+			}
+		})
+	}
+}
+
+func TestAssertionFailures(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeT{TB: t}
+	sink := loggertest.New(f)
+	log := sink.Channel("main")
+
+	log.Error("failed")
+
+	assert.False(t, sink.AssertContains("missing"), "contains should fail")
+	assert.False(t, sink.AssertCount(level.ErrorLevel, 2), "count should fail")
+	assert.False(t, sink.AssertNoneAbove(level.WarnLevel), "none above should fail")
+	assert.Equal(t, 3, f.failures, "wrong failure count")
+}
+
+func TestFields(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, map[string]string{}, loggertest.Fields("no fields here"), "wrong empty fields")
+	assert.Equal(t, map[string]string{"a": "1", "b": ""}, loggertest.Fields("x a=1 b="), "wrong fields")
+}
+
+// not parallel: the global configuration must not reach a sink
+func TestGlobalConfiguration(t *testing.T) {
+	at := time.Date(2014, 8, 12, 10, 44, 35, 0, time.UTC)
+	err := logger.Initialise(logger.Configuration{
+		Directory: t.TempDir(),
+		File:      "test.log",
+		Size:      30000,
+		Count:     10,
+		Levels:    map[string]string{logger.DefaultTag: "critical", "main": "critical"},
+		Clock:     func() time.Time { return at },
+		Redact:    logger.RedactionConfiguration{Fields: []string{"secret"}},
+	})
+	if !assert.Nil(t, err, "initialise error") {
+		return
+	}
+	defer logger.Finalise()
+
+	sink := loggertest.New(t)
+	log := sink.Channel("main")
+	log.Debug("connected secret=abc")
+
+	records := sink.Records()
+	if !assert.Equal(t, 1, len(records), "wrong record count") {
+		return
+	}
+	assert.Equal(t, "connected secret=abc", records[0].Message, "message was redacted")
+	assert.NotEqual(t, at, records[0].Time, "global clock was used")
+}
+
+// created before Capture as a package level channel would be
+var packageLog = logger.New("package")
+
+// not parallel: captures the global output
+func TestCaptureGlobal(t *testing.T) {
+	sink := loggertest.Capture(t, logger.Configuration{
+		Levels: map[string]string{logger.DefaultTag: "info"},
+		Redact: logger.RedactionConfiguration{Fields: []string{"secret"}},
+	})
+
+	packageLog.Debug("below the level")
+	packageLog.Infof("connected secret=%s peer=%d", "abc", 7)
+	logger.New("other").Warn("from another channel")
+
+	assert.False(t, sink.Contains("below the level"), "level not applied")
+	sink.AssertCount(level.InfoLevel, 1)
+	sink.AssertCount(level.WarnLevel, 2) // including the start message
+
+	for _, r := range sink.Records() {
+		if "package" == r.Tag {
+			assert.Equal(t, "connected secret=*** peer=7", r.Message, "message not redacted")
+			assert.Equal(t, "7", r.Fields["peer"], "wrong fields")
+		}
+	}
+	sink.AssertContains("from another channel")
+}
@@ -0,0 +1,319 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package logparser - read log files back into records
+//
+// text records are in the native format:
+//
+//	2014-08-12 10:44:35 [WARN] main: message key=value
+//
+// and may continue over several lines, a record written with the
+// hash chain enabled ends with " #<hash>"
+package logparser
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitmark-inc/logger"
+	"github.com/bitmark-inc/logger/level"
+)
+
+// Record - a parsed log record
+type Record struct {
+	Time      time.Time         // zero if the time could not be determined
+	Level     level.Level       // zero if the level is not known to this program
+	LevelName string            // level as written in the file
+	Tag       string            //
+	Message   string            // without the hash chain suffix
+	Fields    map[string]string // key=value items of the message
+	Hash      string            // hash chain suffix, if present
+	File      string            // file containing the record
+	Line      int               // line number of the first line of the record
+	Text      string            // all lines of the record as written
+}
+
+// DefaultLayouts - time layouts tried when parsing the time of a record
+var DefaultLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+}
+
+// text record lines look like: "<time> [<LEVEL>] <tag>: <message>"
+var recordPattern = regexp.MustCompile(`^(.+?) \[([A-Za-z0-9_]+)\] (.*)$`)
+
+// hash chain suffix
+var hashPattern = regexp.MustCompile(` #[0-9a-f]{64}$`)
+
+// Parser - reads records from one or more sources in order
+//
+// with Follow set the end of the last source is not taken as the end of
+// its last record: Next returns io.EOF leaving the record and any
+// partial line pending, and can be called again once more has been
+// written or another source has been appended
+type Parser struct {
+	Layouts  []string       // time layouts, defaults to DefaultLayouts
+	Location *time.Location // for times without a zone, defaults to time.Local
+	Follow   bool           // wait for more data at the end of the last source
+
+	sources []source
+	reader  *bufio.Reader
+	closer  []io.Closer
+	name    string
+	line    int
+	partial string
+	pending *Record
+}
+
+// a file to open by name or an open reader
+type source struct {
+	name   string
+	reader io.Reader
+}
+
+// NewParser - parse the records from a reader
+func NewParser(r io.Reader) *Parser {
+	return &Parser{
+		reader: bufio.NewReader(r),
+	}
+}
+
+// OpenRotated - parse the records of a log file and its rotated
+// archives, oldest first, including archives compressed after rotation
+func OpenRotated(directory string, file string) (*Parser, error) {
+	files, err := logger.RotatedFiles(directory, file)
+	if nil != err {
+		return nil, err
+	}
+	return OpenFiles(files...), nil
+}
+
+// OpenFiles - parse the records of several files in turn, files ending
+// in ".gz" are decompressed
+func OpenFiles(files ...string) *Parser {
+	p := &Parser{}
+	for _, name := range files {
+		p.sources = append(p.sources, source{name: name})
+	}
+	return p
+}
+
+// Append - parse the records of a reader after those of the current
+// sources, e.g. a log file that replaced the one being followed; the
+// reader is closed at its end if it is an io.Closer
+func (p *Parser) Append(name string, r io.Reader) {
+	p.sources = append(p.sources, source{name: name, reader: r})
+}
+
+// Close - close any open file
+func (p *Parser) Close() error {
+	var err error
+	for _, c := range p.closer {
+		if e := c.Close(); nil == err {
+			err = e
+		}
+	}
+	p.closer = nil
+	p.reader = nil
+	return err
+}
+
+// Next - the next record, io.EOF after the last record
+func (p *Parser) Next() (Record, error) {
+	for {
+		if nil == p.reader {
+			if 0 == len(p.sources) {
+				return p.finish()
+			}
+			// the last record of a file cannot continue into the next
+			if nil != p.pending {
+				return p.finish()
+			}
+			if err := p.open(); nil != err {
+				return Record{}, err
+			}
+		}
+
+		text, err := p.reader.ReadString('\n')
+		if nil != err && io.EOF != err {
+			return Record{}, err
+		}
+		if io.EOF == err {
+			if p.Follow && 0 == len(p.sources) {
+				// the rest of the line has not been written yet
+				p.partial += text
+				return Record{}, io.EOF
+			}
+			p.Close()
+			if "" == text && "" == p.partial {
+				continue
+			}
+		}
+		text = p.partial + text
+		p.partial = ""
+		p.line += 1
+		text = strings.TrimSuffix(text, "\n")
+		text = strings.TrimSuffix(text, "\r")
+
+		r, ok := p.parse(text)
+		if !ok {
+			// a continuation of a multi-line message
+			if nil != p.pending {
+				p.pending.Message += "\n" + text
+				p.pending.Text += "\n" + text
+			} else if "" != text {
+				p.pending = &Record{
+					Message: text,
+					File:    p.name,
+					Line:    p.line,
+					Text:    text,
+				}
+			}
+			continue
+		}
+
+		r.File = p.name
+		r.Line = p.line
+		r.Text = text
+		previous := p.pending
+		p.pending = &r
+		if nil != previous {
+			return complete(*previous), nil
+		}
+	}
+}
+
+// return the last pending record
+func (p *Parser) finish() (Record, error) {
+	if nil == p.pending {
+		return Record{}, io.EOF
+	}
+	r := complete(*p.pending)
+	p.pending = nil
+	return r, nil
+}
+
+// open the next source
+func (p *Parser) open() error {
+	name := p.sources[0].name
+	r := p.sources[0].reader
+	p.sources = p.sources[1:]
+
+	if nil != r {
+		p.closer = nil
+		if c, ok := r.(io.Closer); ok {
+			p.closer = []io.Closer{c}
+		}
+		p.reader = bufio.NewReader(r)
+		p.name = name
+		p.line = 0
+		return nil
+	}
+
+	f, err := os.Open(name)
+	if nil != err {
+		return err
+	}
+	p.closer = []io.Closer{f}
+	r = f
+	if strings.HasSuffix(name, ".gz") {
+		z, err := gzip.NewReader(f)
+		if nil != err {
+			f.Close()
+			return err
+		}
+		p.closer = append([]io.Closer{z}, p.closer...)
+		r = z
+	}
+	p.reader = bufio.NewReader(r)
+	p.name = name
+	p.line = 0
+	return nil
+}
+
+// separate the hash and fields once all lines of a record are known
+func complete(r Record) Record {
+	if h := hashPattern.FindStringIndex(r.Message); nil != h {
+		r.Hash = r.Message[h[0]+2:]
+		r.Message = r.Message[:h[0]]
+	}
+	if nil == r.Fields {
+		r.Fields = logger.Fields(r.Message)
+	}
+	return r
+}
+
+// ParseLine - parse the first line of a record, ok is false if the
+// line is not the start of a record
+func (p *Parser) ParseLine(text string) (Record, bool) {
+	r, ok := p.parse(text)
+	if ok {
+		r = complete(r)
+	}
+	return r, ok
+}
+
+// parse the first line of a text record
+func (p *Parser) parse(text string) (Record, bool) {
+	m := recordPattern.FindStringSubmatch(text)
+	if nil == m {
+		return Record{}, false
+	}
+	t, ok := p.parseTime(m[1])
+	if !ok {
+		return Record{}, false
+	}
+
+	r := Record{
+		Time:      t,
+		LevelName: m[2],
+		Message:   m[3],
+	}
+	if l, err := level.Parse(m[2]); nil == err {
+		r.Level = l
+	}
+	if n := strings.Index(r.Message, ": "); n >= 0 {
+		r.Tag = r.Message[:n]
+		r.Message = r.Message[n+2:]
+	}
+	return r, true
+}
+
+// parse the time of a record using the configured layouts or as
+// seconds or milliseconds since the epoch
+func (p *Parser) parseTime(s string) (time.Time, bool) {
+	layouts := p.Layouts
+	if 0 == len(layouts) {
+		layouts = DefaultLayouts
+	}
+	location := p.Location
+	if nil == location {
+		location = time.Local
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, location); nil == err {
+			return t, true
+		}
+	}
+	return parseEpoch(s)
+}
+
+// seconds or milliseconds since the epoch
+func parseEpoch(s string) (time.Time, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if nil != err || n < 0 {
+		return time.Time{}, false
+	}
+	if n >= 1e12 {
+		return time.UnixMilli(n), true
+	}
+	return time.Unix(n, 0), true
+}
@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logparser_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bitmark-inc/logger"
+	"github.com/bitmark-inc/logger/level"
+	"github.com/bitmark-inc/logger/logparser"
+)
+
+// read all records from a parser
+func readAll(t *testing.T, p *logparser.Parser) []logparser.Record {
+	records := []logparser.Record{}
+	for {
+		r, err := p.Next()
+		if io.EOF == err {
+			return records
+		}
+		if !assert.Nil(t, err, "parse error") {
+			return records
+		}
+		records = append(records, r)
+	}
+}
+
+func TestParseText(t *testing.T) {
+	hash := strings.Repeat("0a", 32)
+	text := "2014-08-12 10:44:35 [WARN] main: message peer=1.2.3.4 note=\"a b\"\n" +
+		"2014-08-12 10:44:36 [ERROR] p2p.peer: first line\n" +
+		"second line\n" +
+		"\n" +
+		"third line #" + hash + "\n" +
+		"2014-08-12 10:44:37 [NOTICE] aux: custom level\n" +
+		"2014-08-12 10:44:38 [INFO] main: partial"
+
+	p := logparser.NewParser(strings.NewReader(text))
+	p.Location = time.UTC
+	records := readAll(t, p)
+	if !assert.Equal(t, 4, len(records), "wrong record count") {
+		return
+	}
+
+	r := records[0]
+	assert.Equal(t, time.Date(2014, 8, 12, 10, 44, 35, 0, time.UTC), r.Time, "wrong time")
+	assert.Equal(t, level.Level(level.WarnLevel), r.Level, "wrong level")
+	assert.Equal(t, "WARN", r.LevelName, "wrong level name")
+	assert.Equal(t, "main", r.Tag, "wrong tag")
+	assert.Equal(t, `message peer=1.2.3.4 note="a b"`, r.Message, "wrong message")
+	assert.Equal(t, map[string]string{"peer": "1.2.3.4", "note": "a b"}, r.Fields, "wrong fields")
+	assert.Equal(t, 1, r.Line, "wrong line")
+
+	r = records[1]
+	assert.Equal(t, "p2p.peer", r.Tag, "wrong tag")
+	assert.Equal(t, "first line\nsecond line\n\nthird line", r.Message, "wrong multi-line message")
+	assert.Equal(t, hash, r.Hash, "wrong hash")
+	assert.Equal(t, 2, r.Line, "wrong line")
+
+	r = records[2]
+	assert.Equal(t, level.Level(0), r.Level, "unknown level should be zero")
+	assert.Equal(t, "NOTICE", r.LevelName, "wrong level name")
+
+	r = records[3]
+	assert.Equal(t, "partial", r.Message, "wrong partial message")
+}
+
+func TestParseTimestamps(t *testing.T) {
+	text := "2014-08-12T10:44:35.123Z [INFO] main: rfc3339\n" +
+		"1407840275 [INFO] main: unix\n" +
+		"1407840275123 [INFO] main: unixmilli\n"
+
+	records := readAll(t, logparser.NewParser(strings.NewReader(text)))
+	if !assert.Equal(t, 3, len(records), "wrong record count") {
+		return
+	}
+	expected := time.Date(2014, 8, 12, 10, 44, 35, 123000000, time.UTC)
+	assert.True(t, expected.Equal(records[0].Time), "wrong rfc3339 time: %s", records[0].Time)
+	assert.True(t, expected.Truncate(time.Second).Equal(records[1].Time), "wrong unix time: %s", records[1].Time)
+	assert.True(t, expected.Equal(records[2].Time), "wrong unixmilli time: %s", records[2].Time)
+}
+
+func TestFollow(t *testing.T) {
+	name := path.Join(t.TempDir(), "app.log")
+	f, err := os.Create(name)
+	if !assert.Nil(t, err, "create error") {
+		return
+	}
+	defer f.Close()
+	input, err := os.Open(name)
+	if !assert.Nil(t, err, "open error") {
+		return
+	}
+
+	p := logparser.NewParser(input)
+	p.Follow = true
+	defer p.Close()
+
+	// the last record is held until the next one starts
+	f.WriteString("2014-08-12 10:44:35 [INFO] main: one\ncontin")
+	assert.Equal(t, 0, len(readAll(t, p)), "incomplete record returned")
+
+	// nor can it complete while the next header is only partly written
+	f.WriteString("ued\n2014-08-12 10:44:36 [INFO] main: t")
+	assert.Equal(t, 0, len(readAll(t, p)), "record returned before the next header")
+
+	// a new source ends the records of the previous one
+	f.WriteString("wo\n")
+	p.Append("new", strings.NewReader("2014-08-12 10:44:37 [INFO] main: three\n"))
+	records := readAll(t, p)
+	if assert.Equal(t, 2, len(records), "wrong record count") {
+		assert.Equal(t, "one\ncontinued", records[0].Message, "wrong message")
+		assert.Equal(t, "2014-08-12 10:44:35 [INFO] main: one\ncontinued", records[0].Text, "wrong text")
+		assert.Equal(t, "two", records[1].Message, "wrong message")
+		assert.Equal(t, 3, records[1].Line, "wrong line")
+	}
+
+	p.Follow = false
+	records = readAll(t, p)
+	if assert.Equal(t, 1, len(records), "wrong record count") {
+		assert.Equal(t, "three", records[0].Message, "wrong message")
+		assert.Equal(t, "new", records[0].File, "wrong file")
+	}
+}
+
+func TestOpenRotated(t *testing.T) {
+	directory := t.TempDir()
+	write := func(name string, text string) {
+		err := os.WriteFile(path.Join(directory, name), []byte(text), 0o600)
+		assert.Nil(t, err, "write error")
+	}
+	write("app.log.1", "2014-08-12 10:00:00 [INFO] main: one\n")
+	write("app.log.2", "2014-08-12 10:00:01 [INFO] main: two\ncontinued")
+	write("app.log", "2014-08-12 10:00:02 [INFO] main: three\n")
+	write("app.log.x", "2014-08-12 09:00:00 [INFO] main: ignored\n")
+
+	// the oldest archive has been compressed
+	f, err := os.Create(path.Join(directory, "app.log.0.gz"))
+	if !assert.Nil(t, err, "create error") {
+		return
+	}
+	z := gzip.NewWriter(f)
+	z.Write([]byte("2014-08-12 09:59:59 [INFO] main: zero\n"))
+	z.Close()
+	f.Close()
+
+	p, err := logparser.OpenRotated(directory, "app.log")
+	if !assert.Nil(t, err, "open error") {
+		return
+	}
+	defer p.Close()
+
+	records := readAll(t, p)
+	messages := []string{}
+	for _, r := range records {
+		messages = append(messages, r.Message)
+	}
+	assert.Equal(t, []string{"zero", "one", "two\ncontinued", "three"}, messages, "wrong record order")
+	assert.Equal(t, path.Join(directory, "app.log.0.gz"), records[0].File, "wrong file")
+	assert.Equal(t, path.Join(directory, "app.log.2"), records[2].File, "wrong file")
+	assert.Equal(t, 1, records[3].Line, "wrong line")
+}
+
+func TestOpenCompressed(t *testing.T) {
+	directory := t.TempDir()
+	name := path.Join(directory, "app.log.1.gz")
+	f, err := os.Create(name)
+	if !assert.Nil(t, err, "create error") {
+		return
+	}
+	z := gzip.NewWriter(f)
+	z.Write([]byte("2014-08-12 10:00:00 [INFO] main: compressed\n"))
+	z.Close()
+	f.Close()
+
+	p := logparser.OpenFiles(name)
+	defer p.Close()
+
+	records := readAll(t, p)
+	if assert.Equal(t, 1, len(records), "wrong record count") {
+		assert.Equal(t, "compressed", records[0].Message, "wrong message")
+	}
+}
+
+func TestParseLoggerOutput(t *testing.T) {
+	directory := t.TempDir()
+	c := logger.Configuration{
+		Directory: directory,
+		File:      "parse.log",
+		Size:      50000,
+		Count:     10,
+		Levels:    map[string]string{logger.DefaultTag: "info"},
+		Chain:     true,
+	}
+	if !assert.Nil(t, logger.Initialise(c), "initialise error") {
+		return
+	}
+	log := logger.New("parse")
+	log.Warnf("connected peer=%s", "1.2.3.4")
+	log.Info("first\nsecond")
+	log.Debug("not logged")
+	logger.Finalise()
+
+	p, err := logparser.OpenRotated(directory, "parse.log")
+	if !assert.Nil(t, err, "open error") {
+		return
+	}
+	defer p.Close()
+
+	records := []logparser.Record{}
+	for _, r := range readAll(t, p) {
+		if "parse" == r.Tag {
+			records = append(records, r)
+		}
+	}
+	if !assert.Equal(t, 2, len(records), "wrong record count") {
+		return
+	}
+	assert.Equal(t, level.Level(level.WarnLevel), records[0].Level, "wrong level")
+	assert.Equal(t, "1.2.3.4", records[0].Fields["peer"], "wrong field")
+	assert.Equal(t, 64, len(records[0].Hash), "missing hash")
+	assert.Equal(t, "first\nsecond", records[1].Message, "wrong multi-line message")
+	assert.WithinDuration(t, time.Now(), records[1].Time, time.Minute, "wrong time")
+}
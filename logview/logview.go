@@ -0,0 +1,266 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// display records from a log file and its rotated archives in order
+//
+// usage:
+//
+//	logview -directory /var/lib/app/log -file app.log [options]
+//	logview -config app.yaml [options]
+//
+// options:
+//
+//	-tag main          only records from a tag, or "p2p.*" for a subtree
+//	-min-level warn    only records at or above a level
+//	-max-level error   only records at or below a level
+//	-since 2014-08-12T10:00:00Z
+//	-until 2014-08-12T11:00:00Z
+//	-match 'peer.*lost'
+//	-follow            keep reading as records are added
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bitmark-inc/logger"
+	"github.com/bitmark-inc/logger/level"
+	"github.com/bitmark-inc/logger/logparser"
+)
+
+// how often to check for new records when following
+const followInterval = 500 * time.Millisecond
+
+// selection criteria
+type selection struct {
+	tag      string
+	minLevel int
+	maxLevel int
+	since    time.Time
+	until    time.Time
+	match    *regexp.Regexp
+}
+
+func main() {
+	configFile := flag.String("config", "", "JSON, YAML or TOML file containing a logger configuration")
+	directory := flag.String("directory", ".", "directory containing the log files")
+	file := flag.String("file", "", "name of the current log file")
+	tag := flag.String("tag", "", "only show this tag or tag pattern")
+	minLevel := level.Level(level.TraceLevel)
+	flag.Var(&minLevel, "min-level", "only show records at or above this level")
+	maxLevel := level.Level(level.CriticalLevel)
+	flag.Var(&maxLevel, "max-level", "only show records at or below this level")
+	since := flag.String("since", "", "only show records from this time (RFC3339)")
+	until := flag.String("until", "", "only show records before this time (RFC3339)")
+	match := flag.String("match", "", "only show records whose message matches this regular expression")
+	follow := flag.Bool("follow", false, "wait for new records")
+	flag.Parse()
+
+	if "" != *configFile {
+		c, err := logger.LoadConfigurationFile(*configFile)
+		if nil != err {
+			exitWithError(err)
+		}
+		*directory = c.Directory
+		*file = c.File
+	}
+	if "" == *file {
+		fmt.Fprintf(os.Stderr, "logview: -file or -config is required\n")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	s := selection{
+		tag:      *tag,
+		minLevel: int(minLevel),
+		maxLevel: int(maxLevel),
+	}
+	var err error
+	if "" != *since {
+		if s.since, err = time.Parse(time.RFC3339, *since); nil != err {
+			exitWithError(err)
+		}
+	}
+	if "" != *until {
+		if s.until, err = time.Parse(time.RFC3339, *until); nil != err {
+			exitWithError(err)
+		}
+	}
+	if "" != *match {
+		if s.match, err = regexp.Compile(*match); nil != err {
+			exitWithError(err)
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	err = view(out, *directory, *file, s, *follow)
+	if nil != err {
+		out.Flush()
+		exitWithError(err)
+	}
+}
+
+func exitWithError(err error) {
+	fmt.Fprintf(os.Stderr, "logview: %s\n", err)
+	os.Exit(1)
+}
+
+// list the archives, possibly gzip compressed, oldest first, without
+// the current file
+func listArchives(directory string, file string) ([]string, error) {
+	files, err := logger.RotatedFiles(directory, file)
+	if nil != err {
+		return nil, err
+	}
+	if n := len(files); 0 != n && path.Join(directory, file) == files[n-1] {
+		files = files[:n-1]
+	}
+	return files, nil
+}
+
+// output the selected records of the archives and then the current
+// file, a record is only complete once the next one starts, the file
+// is rotated or, without follow, at the end of the file
+func view(out *bufio.Writer, directory string, file string, s selection, follow bool) error {
+	archives, err := listArchives(directory, file)
+	if nil != err {
+		return err
+	}
+
+	p := logparser.OpenFiles(archives...)
+	p.Follow = follow
+	defer p.Close()
+
+	err = copyRecords(out, p, s)
+	if nil != err {
+		return err
+	}
+
+	current := path.Join(directory, file)
+	f, err := os.Open(current)
+	if os.IsNotExist(err) && follow {
+		out.Flush()
+		f, err = waitForFile(current)
+	}
+	if nil != err {
+		if os.IsNotExist(err) && 0 != len(archives) {
+			return nil
+		}
+		return err
+	}
+	p.Append(current, f)
+
+	for {
+		err := copyRecords(out, p, s)
+		if nil != err {
+			return err
+		}
+		if !follow {
+			return nil
+		}
+		out.Flush()
+
+		// wait for more data or a rotation
+		time.Sleep(followInterval)
+		rotated, err := isRotated(f, current)
+		if nil != err {
+			return err
+		}
+		if rotated {
+			// the parser finishes the old file, then starts on the
+			// new one
+			f, err = waitForFile(current)
+			if nil != err {
+				return err
+			}
+			p.Append(current, f)
+		}
+	}
+}
+
+// open a file once it exists
+func waitForFile(name string) (*os.File, error) {
+	for {
+		f, err := os.Open(name)
+		if !os.IsNotExist(err) {
+			return f, err
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+// check if the open file is no longer the current log file
+func isRotated(f *os.File, name string) (bool, error) {
+	opened, err := f.Stat()
+	if nil != err {
+		return false, err
+	}
+	current, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if nil != err {
+		return false, err
+	}
+	return !os.SameFile(opened, current), nil
+}
+
+// write the selected records that are complete
+func copyRecords(out *bufio.Writer, p *logparser.Parser, s selection) error {
+	for {
+		r, err := p.Next()
+		if io.EOF == err {
+			return nil
+		}
+		if nil != err {
+			return err
+		}
+		if s.selected(&r) {
+			out.WriteString(r.Text)
+			out.WriteByte('\n')
+		}
+	}
+}
+
+// check a record against the selection criteria
+func (s selection) selected(r *logparser.Record) bool {
+	if "" != s.tag {
+		if strings.HasSuffix(s.tag, ".*") {
+			prefix := strings.TrimSuffix(s.tag, "*")
+			if !strings.HasPrefix(r.Tag, prefix) || len(r.Tag) == len(prefix) {
+				return false
+			}
+		} else if r.Tag != s.tag {
+			return false
+		}
+	}
+	if 0 == r.Level {
+		// levels unknown to this program are only shown when no
+		// level range was requested
+		if level.TraceLevel != s.minLevel || level.CriticalLevel != s.maxLevel {
+			return false
+		}
+	} else if int(r.Level) < s.minLevel || int(r.Level) > s.maxLevel {
+		return false
+	}
+	if !s.since.IsZero() && (r.Time.IsZero() || r.Time.Before(s.since)) {
+		return false
+	}
+	if !s.until.IsZero() && (r.Time.IsZero() || !r.Time.Before(s.until)) {
+		return false
+	}
+	if nil != s.match && !s.match.MatchString(r.Message) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bitmark-inc/logger/level"
+	"github.com/bitmark-inc/logger/logparser"
+)
+
+func TestSelected(t *testing.T) {
+	at := time.Date(2014, 8, 12, 10, 44, 35, 0, time.UTC)
+	all := selection{
+		minLevel: level.TraceLevel,
+		maxLevel: level.CriticalLevel,
+	}
+	with := func(change func(s *selection)) selection {
+		s := all
+		change(&s)
+		return s
+	}
+
+	cases := []struct {
+		name      string
+		selection selection
+		record    logparser.Record
+		expected  bool
+	}{
+		{"everything", all, logparser.Record{Tag: "main", Level: level.Level(level.InfoLevel), Time: at}, true},
+		{"tag", with(func(s *selection) { s.tag = "main" }), logparser.Record{Tag: "main", Level: level.Level(level.InfoLevel)}, true},
+		{"other tag", with(func(s *selection) { s.tag = "main" }), logparser.Record{Tag: "aux", Level: level.Level(level.InfoLevel)}, false},
+		{"tag prefix", with(func(s *selection) { s.tag = "main" }), logparser.Record{Tag: "mainly", Level: level.Level(level.InfoLevel)}, false},
+		{"subtree", with(func(s *selection) { s.tag = "p2p.*" }), logparser.Record{Tag: "p2p.peer", Level: level.Level(level.InfoLevel)}, true},
+		{"subtree root", with(func(s *selection) { s.tag = "p2p.*" }), logparser.Record{Tag: "p2p", Level: level.Level(level.InfoLevel)}, false},
+		{"subtree sibling", with(func(s *selection) { s.tag = "p2p.*" }), logparser.Record{Tag: "p2p2", Level: level.Level(level.InfoLevel)}, false},
+		{"below minimum", with(func(s *selection) { s.minLevel = level.WarnLevel }), logparser.Record{Level: level.Level(level.InfoLevel)}, false},
+		{"at minimum", with(func(s *selection) { s.minLevel = level.WarnLevel }), logparser.Record{Level: level.Level(level.WarnLevel)}, true},
+		{"above maximum", with(func(s *selection) { s.maxLevel = level.WarnLevel }), logparser.Record{Level: level.Level(level.ErrorLevel)}, false},
+		{"unknown level", all, logparser.Record{Level: 0}, true},
+		{"unknown level with range", with(func(s *selection) { s.minLevel = level.WarnLevel }), logparser.Record{Level: 0}, false},
+		{"since", with(func(s *selection) { s.since = at }), logparser.Record{Level: level.Level(level.InfoLevel), Time: at}, true},
+		{"before since", with(func(s *selection) { s.since = at.Add(time.Second) }), logparser.Record{Level: level.Level(level.InfoLevel), Time: at}, false},
+		{"since without time", with(func(s *selection) { s.since = at }), logparser.Record{Level: level.Level(level.InfoLevel)}, false},
+		{"until", with(func(s *selection) { s.until = at }), logparser.Record{Level: level.Level(level.InfoLevel), Time: at}, false},
+		{"before until", with(func(s *selection) { s.until = at.Add(time.Second) }), logparser.Record{Level: level.Level(level.InfoLevel), Time: at}, true},
+		{"match", with(func(s *selection) { s.match = regexp.MustCompile("peer.*lost") }), logparser.Record{Level: level.Level(level.InfoLevel), Message: "peer 1 lost"}, true},
+		{"no match", with(func(s *selection) { s.match = regexp.MustCompile("peer.*lost") }), logparser.Record{Level: level.Level(level.InfoLevel), Message: "peer 1 found"}, false},
+		{"match continuation", with(func(s *selection) { s.match = regexp.MustCompile("lost") }), logparser.Record{Level: level.Level(level.InfoLevel), Message: "peer 1\nlost"}, true},
+	}
+	for _, c := range cases {
+		r := c.record
+		assert.Equal(t, c.expected, c.selection.selected(&r), "wrong selection: %s", c.name)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	all := selection{minLevel: level.TraceLevel, maxLevel: level.CriticalLevel}
+	cases := []struct {
+		name      string
+		selection selection
+		parts     []string // written in turn, each followed by a poll
+		expected  []string // output after each poll
+		final     string   // output once following stops
+	}{
+		{
+			name:      "complete lines",
+			selection: all,
+			parts:     []string{"2014-08-12 10:44:35 [INFO] main: one\n2014-08-12 10:44:36 [INFO] main: two\n"},
+			expected:  []string{"2014-08-12 10:44:35 [INFO] main: one\n"},
+			final:     "2014-08-12 10:44:36 [INFO] main: two\n",
+		},
+		{
+			name:      "partial line",
+			selection: all,
+			parts:     []string{"2014-08-12 10:44:35 [INFO] main: o", "ne\n2014-08-12 10:44:36 [INFO] main: two\n"},
+			expected:  []string{"", "2014-08-12 10:44:35 [INFO] main: one\n"},
+			final:     "2014-08-12 10:44:36 [INFO] main: two\n",
+		},
+		{
+			name:      "continuation completed later",
+			selection: all,
+			parts:     []string{"2014-08-12 10:44:35 [INFO] main: first\nsec", "ond\n", "2014-08-12 10:44:36 [INFO] main: third\n"},
+			expected:  []string{"", "", "2014-08-12 10:44:35 [INFO] main: first\nsecond\n"},
+			final:     "2014-08-12 10:44:36 [INFO] main: third\n",
+		},
+		{
+			name:      "continuation of an unselected record",
+			selection: selection{tag: "main", minLevel: level.TraceLevel, maxLevel: level.CriticalLevel},
+			parts:     []string{"2014-08-12 10:44:35 [INFO] aux: first\n", "second\n", "2014-08-12 10:44:36 [INFO] main: third\n"},
+			expected:  []string{"", "", ""},
+			final:     "2014-08-12 10:44:36 [INFO] main: third\n",
+		},
+	}
+
+	for _, c := range cases {
+		name := path.Join(t.TempDir(), "app.log")
+		f, err := os.Create(name)
+		if !assert.Nil(t, err, "create error") {
+			return
+		}
+		input, err := os.Open(name)
+		if !assert.Nil(t, err, "open error") {
+			return
+		}
+
+		p := logparser.NewParser(input)
+		p.Follow = true
+
+		var buffer bytes.Buffer
+		out := bufio.NewWriter(&buffer)
+		for i, part := range c.parts {
+			f.WriteString(part)
+			err := copyRecords(out, p, c.selection)
+			assert.Nil(t, err, "copy error: %s", c.name)
+			out.Flush()
+			assert.Equal(t, c.expected[i], buffer.String(), "wrong output: %s part: %d", c.name, i)
+			buffer.Reset()
+		}
+
+		p.Follow = false
+		err = copyRecords(out, p, c.selection)
+		assert.Nil(t, err, "copy error: %s", c.name)
+		out.Flush()
+		assert.Equal(t, c.final, buffer.String(), "wrong final output: %s", c.name)
+
+		f.Close()
+		input.Close()
+	}
+}
+
+func TestView(t *testing.T) {
+	directory := t.TempDir()
+	files := map[string]string{
+		"app.log.1": "2014-08-12 10:44:35 [INFO] main: one\n2014-08-12 10:44:36 [WARN] aux: two\ncontinued\n",
+		"app.log":   "2014-08-12 10:44:37 [INFO] main: three\n2014-08-12 10:44:38 [INFO] main: four",
+	}
+	for name, text := range files {
+		err := os.WriteFile(path.Join(directory, name), []byte(text), 0o600)
+		assert.Nil(t, err, "write error")
+	}
+
+	var buffer bytes.Buffer
+	out := bufio.NewWriter(&buffer)
+	err := view(out, directory, "app.log", selection{minLevel: level.TraceLevel, maxLevel: level.CriticalLevel}, false)
+	assert.Nil(t, err, "view error")
+	out.Flush()
+	assert.Equal(t, "2014-08-12 10:44:35 [INFO] main: one\n"+
+		"2014-08-12 10:44:36 [WARN] aux: two\ncontinued\n"+
+		"2014-08-12 10:44:37 [INFO] main: three\n"+
+		"2014-08-12 10:44:38 [INFO] main: four\n", buffer.String(), "wrong output")
+}
+
+func TestListArchives(t *testing.T) {
+	directory := t.TempDir()
+	for _, name := range []string{"app.log", "app.log.2.gz", "app.log.1", "app.log.x", "other.log.3"} {
+		err := os.WriteFile(path.Join(directory, name), []byte("x\n"), 0o600)
+		assert.Nil(t, err, "write error")
+	}
+
+	archives, err := listArchives(directory, "app.log")
+	assert.Nil(t, err, "wrong listArchives")
+	assert.Equal(t, []string{
+		path.Join(directory, "app.log.1"),
+		path.Join(directory, "app.log.2.gz"),
+	}, archives, "wrong archives")
+
+	// only archives remain when the current file was rotated away
+	os.Remove(path.Join(directory, "app.log"))
+	archives, err = listArchives(directory, "app.log")
+	assert.Nil(t, err, "wrong listArchives")
+	assert.Equal(t, 2, len(archives), "wrong archive count")
+	assert.False(t, strings.HasSuffix(archives[1], "app.log"), "current file listed")
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevels(t *testing.T) {
+	assert.Equal(t, []string{"Trace", "Debug", "Info", "Warn", "Error", "Critical"}, levels(), "wrong levels")
+}
+
+func TestGenerate(t *testing.T) {
+	code, err := generate()
+	if !assert.Nil(t, err, "generate error") {
+		return
+	}
+
+	for _, name := range levels() {
+		for _, suffix := range []string{"", "f", "c"} {
+			method := name + suffix
+			assert.True(t, bytes.Contains(code, []byte("func (l *L) "+method+"(")), "missing channel method: %s", method)
+			assert.True(t, bytes.Contains(code, []byte("func (NopLogger) "+method+"(")), "missing no-op method: %s", method)
+			assert.True(t, bytes.Contains(code, []byte("func (m *MockLogger) "+method+"(")), "missing mock method: %s", method)
+		}
+	}
+
+	// the same check as: go run ./makeloggerinterface -output interface.go -check
+	current, err := os.ReadFile("../interface.go")
+	if assert.Nil(t, err, "read error") {
+		assert.True(t, bytes.Equal(current, code), "interface.go is out of date, run: go generate")
+	}
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+// names of outputs for Configuration.OutputLevels
+const (
+	OutputFile    = "file"
+	OutputConsole = "console"
+)
+
+// OutputLevel - minimum level delivered to an output
+type OutputLevel struct {
+	Output   string `json:"output"`
+	LogLevel string `json:"category"`
+}
+
+// check the output levels, returning the level for every output;
+// outputs without a level receive all messages and at least one output
+// in use (the file and, if enabled, the console) must not be "off"
+//
+// channel levels decide which messages are generated, output levels
+// then decide where they are delivered.  Only standard levels can be
+// used since outputs filter on the seelog level, so custom levels are
+// treated as their base level.
+func validateOutputLevels(outputLevels map[string]string, console bool) (map[string]string, error) {
+	result := map[string]string{
+		OutputFile:    level.Trace,
+		OutputConsole: level.Trace,
+	}
+	errs := ValidationErrors{}
+	for _, output := range sortedKeys(outputLevels) {
+		name := outputLevels[output]
+		field := "OutputLevels[" + output + "]"
+		if _, ok := result[output]; !ok {
+			errs.add(&FieldError{Field: field, Value: name, Reason: "is not an output"})
+			continue
+		}
+		n, ok := level.Number(name)
+		if !ok || 0 != n%level.Spacing {
+			errs.add(&FieldError{Field: field, Value: name, Reason: "is not a standard level"})
+			continue
+		}
+		result[output] = name
+	}
+	if 0 != len(errs) {
+		return nil, errs
+	}
+
+	// seelog cannot be configured without any output
+	fileLevel, _ := level.Number(result[OutputFile])
+	consoleLevel, _ := level.Number(result[OutputConsole])
+	if level.OffLevel == fileLevel && (!console || level.OffLevel == consoleLevel) {
+		return nil, &FieldError{Field: "OutputLevels[" + OutputFile + "]", Value: result[OutputFile], Reason: "leaves no output enabled"}
+	}
+	return result, nil
+}
+
+// wrap a seelog output element so it only receives messages at or
+// above a level
+func filterOutput(levelName string, element string) string {
+	minimum, _ := level.Number(levelName)
+	if minimum <= level.TraceLevel {
+		return element
+	}
+	if minimum >= level.OffLevel {
+		return ""
+	}
+
+	levels := make([]string, 0, 6)
+	for _, d := range level.Definitions() {
+		if d.Standard() && d.Number >= minimum && d.Number < level.OffLevel {
+			levels = append(levels, d.Name)
+		}
+	}
+	return `<filter levels="` + strings.Join(levels, ",") + `">` + element + `</filter>`
+}
+
+// the output levels in a fixed order for ListLevels
+// globalData must be locked by caller
+func listOutputLevels() []OutputLevel {
+	outputs := make([]string, 0, len(globalData.outputLevels))
+	for output := range globalData.outputLevels {
+		outputs = append(outputs, output)
+	}
+	sort.Strings(outputs)
+
+	result := make([]OutputLevel, 0, len(outputs))
+	for _, output := range outputs {
+		result = append(result, OutputLevel{
+			Output:   output,
+			LogLevel: globalData.outputLevels[output],
+		})
+	}
+	return result
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+)
+
+// reasons for a DirectoryError, use with errors.Is
+var (
+	ErrDirectoryMissing = errors.New("does not exist")
+	ErrNotDirectory     = errors.New("is not a directory")
+	ErrNotWritable      = errors.New("is not writable")
+)
+
+// DirectoryError - the log directory cannot be used
+type DirectoryError struct {
+	Directory string
+	Err       error // one of ErrDirectoryMissing, ErrNotDirectory or ErrNotWritable
+	Cause     error // underlying error, if any
+}
+
+func (e *DirectoryError) Error() string {
+	if nil == e.Cause {
+		return fmt.Sprintf("Directory: %q %s", e.Directory, e.Err)
+	}
+	return fmt.Sprintf("Directory: %q %s: %s", e.Directory, e.Err, e.Cause)
+}
+
+func (e *DirectoryError) Unwrap() error {
+	return e.Err
+}
+
+// mode and ownership for log files, zero mode and negative ids leave
+// the defaults unchanged
+type filePermissions struct {
+	mode os.FileMode
+	uid  int
+	gid  int
+}
+
+// check the permission settings of a configuration
+func validatePermissions(configuration Configuration) (os.FileMode, filePermissions, error) {
+	permissions := filePermissions{uid: -1, gid: -1}
+	errs := ValidationErrors{}
+
+	directoryMode, err := parseMode("DirectoryMode", configuration.DirectoryMode, 0o750)
+	errs.add(err)
+	permissions.mode, err = parseMode("FileMode", configuration.FileMode, 0)
+	errs.add(err)
+
+	if "" != configuration.Owner {
+		u, err := user.Lookup(configuration.Owner)
+		if nil != err {
+			u, err = user.LookupId(configuration.Owner)
+		}
+		if nil != err {
+			errs.add(&FieldError{Field: "Owner", Value: configuration.Owner, Reason: "is not a known user"})
+		} else {
+			permissions.uid, _ = strconv.Atoi(u.Uid)
+		}
+	}
+	if "" != configuration.Group {
+		g, err := user.LookupGroup(configuration.Group)
+		if nil != err {
+			g, err = user.LookupGroupId(configuration.Group)
+		}
+		if nil != err {
+			errs.add(&FieldError{Field: "Group", Value: configuration.Group, Reason: "is not a known group"})
+		} else {
+			permissions.gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+
+	return directoryMode, permissions, errs.err()
+}
+
+// parse an octal permission string e.g. "0640"
+func parseMode(name string, s string, defaultMode os.FileMode) (os.FileMode, error) {
+	if "" == s {
+		return defaultMode, nil
+	}
+	n, err := strconv.ParseUint(s, 8, 32)
+	if nil != err || n > 0o777 {
+		return 0, &FieldError{Field: name, Value: s, Reason: "is not an octal permission"}
+	}
+	return os.FileMode(n), nil
+}
+
+// create a directory and any missing parents with a mode that, unlike
+// that of os.MkdirAll, is not reduced by the umask; directories that
+// already exist are left unchanged
+func makeDirectory(directory string, mode os.FileMode) error {
+	created := []string{}
+	for d := path.Clean(directory); ; d = path.Dir(d) {
+		if _, err := os.Stat(d); !os.IsNotExist(err) {
+			break
+		}
+		created = append(created, d)
+		if path.Dir(d) == d {
+			break
+		}
+	}
+
+	err := os.MkdirAll(directory, mode)
+	if nil != err {
+		return err
+	}
+	for _, d := range created {
+		if err := os.Chmod(d, mode); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensure that the log directory exists, creating it if allowed, and
+// that a log file can be written to it
+func prepareDirectory(directory string, create bool, mode os.FileMode, filepath string, permissions filePermissions) error {
+	info, err := os.Stat(directory)
+	if os.IsNotExist(err) && create {
+		err = makeDirectory(directory, mode)
+		if nil != err {
+			return &DirectoryError{Directory: directory, Err: ErrNotWritable, Cause: err}
+		}
+		info, err = os.Stat(directory)
+	}
+	if os.IsNotExist(err) {
+		return &DirectoryError{Directory: directory, Err: ErrDirectoryMissing}
+	} else if nil != err {
+		return err
+	}
+	if !info.IsDir() {
+		return &DirectoryError{Directory: directory, Err: ErrNotDirectory}
+	}
+
+	// probe with a unique name so that no existing file is affected
+	probe, err := os.CreateTemp(directory, ".probe-*")
+	if nil != err {
+		return &DirectoryError{Directory: directory, Err: ErrNotWritable, Cause: err}
+	}
+	_, err = probe.Write([]byte("0123456789"))
+	probe.Close()
+	os.Remove(probe.Name())
+	if nil != err {
+		return &DirectoryError{Directory: directory, Err: ErrNotWritable, Cause: err}
+	}
+
+	// an existing log file must be appendable, a new one is only
+	// created here if its permissions are to be set
+	flags := os.O_WRONLY | os.O_APPEND
+	if permissions.required() {
+		flags |= os.O_CREATE
+	}
+	fd, err := os.OpenFile(filepath, flags, 0o666)
+	if os.IsNotExist(err) {
+		return nil
+	} else if nil != err {
+		return &DirectoryError{Directory: directory, Err: ErrNotWritable, Cause: err}
+	}
+	fd.Close()
+	return permissions.apply(filepath)
+}
+
+// set the mode and ownership of a file
+func (p filePermissions) apply(name string) error {
+	if 0 != p.mode {
+		if err := os.Chmod(name, p.mode); nil != err {
+			return err
+		}
+	}
+	if p.uid >= 0 || p.gid >= 0 {
+		if err := os.Chown(name, p.uid, p.gid); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// check if any permissions are to be set
+func (p filePermissions) required() bool {
+	return 0 != p.mode || p.uid >= 0 || p.gid >= 0
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cihub/seelog"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+// Record - a single log message as passed to a Receiver
+type Record struct {
+	Time    time.Time
+	Level   level.Level
+	Tag     string
+	Message string
+}
+
+// Receiver - destination for the messages of a detached channel
+type Receiver interface {
+	Receive(record Record)
+}
+
+// NewDetached - open a logging channel that is independent of the
+// global output and level configuration
+//
+// every message at or above the given level is passed synchronously to
+// the receiver; the channel is not listed by ListLevels and is not
+// affected by UpdateTagLogLevel, the configured Clock or redaction,
+// which makes it suitable for tests
+func NewDetached(tag string, levelName string, receiver Receiver) (*L, error) {
+	num, ok := level.Number(levelName)
+	if !ok {
+		return nil, fmt.Errorf("level %s invalid", levelName)
+	}
+
+	config := `
+          <seelog type="sync" minlevel="trace">
+              <outputs formatid="raw">
+                  <custom name="receiver" />
+              </outputs>
+              <formats>
+                  <format id="raw" format="%Msg" />
+              </formats>
+          </seelog>`
+
+	s := strings.Split(tag, "%")
+	j := strings.Join(s, "%%")
+
+	params := &seelog.CfgParseParams{
+		CustomReceiverProducers: map[string]seelog.CustomReceiverProducer{
+			"receiver": func(seelog.CustomReceiverInitArgs) (seelog.CustomReceiver, error) {
+				return &receiverAdapter{
+					prefix:   tag + tagSuffix,
+					tag:      tag,
+					receiver: receiver,
+				}, nil
+			},
+		},
+	}
+	log, err := seelog.LoggerFromParamConfigAsString(config, params)
+	if nil != err {
+		return nil, err
+	}
+
+	l := &L{
+		tag:          tag,
+		formatPrefix: j + tagSuffix,
+		textPrefix:   tag + tagSuffix,
+		detached:     true,
+	}
+	l.binding.Store(&binding{
+		log:         log,
+		level:       levelName,
+		levelNumber: num,
+	})
+	return l, nil
+}
+
+// name of the custom receiver that replaces the log file when a
+// Configuration has a Receiver
+const recordReceiver = "records"
+
+// converts seelog messages back to records
+//
+// a detached channel has a single tag, the global output carries
+// messages of all tags and has redaction applied as for the log file
+type receiverAdapter struct {
+	prefix   string
+	tag      string
+	global   bool
+	receiver Receiver
+}
+
+func (r *receiverAdapter) ReceiveMessage(message string, l seelog.LogLevel, context seelog.LogContextInterface) error {
+	t := messageTime(message, context)
+	n := recordLevel(message, l)
+	_, message, _ = splitMarker(message)
+
+	tag := r.tag
+	if r.global {
+		message = redact(message)
+		tag = ""
+		if i := strings.Index(message, tagSuffix); i >= 0 {
+			tag = message[:i]
+			message = message[i+len(tagSuffix):]
+		}
+	} else {
+		message = strings.TrimPrefix(message, r.prefix)
+	}
+
+	r.receiver.Receive(Record{
+		Time:    t,
+		Level:   n,
+		Tag:     tag,
+		Message: message,
+	})
+	return nil
+}
+
+func (r *receiverAdapter) AfterParse(seelog.CustomReceiverInitArgs) error {
+	return nil
+}
+
+func (r *receiverAdapter) Flush() {
+}
+
+func (r *receiverAdapter) Close() error {
+	return nil
+}
+
+// recover the level number from a seelog message
+func recordLevel(message string, l seelog.LogLevel) level.Level {
+	if display, _, ok := splitMarker(message); ok {
+		for _, d := range level.Definitions() {
+			if d.Display == display {
+				return level.Level(d.Number)
+			}
+		}
+	}
+	n, ok := level.Number(l.String())
+	if !ok {
+		return level.Level(level.CriticalLevel)
+	}
+	return level.Level(n)
+}
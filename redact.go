@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// the replacement for any redacted text
+const redactedText = "***"
+
+// Redacted - wrap a value so that it is never written to the log
+// e.g.
+//   log.Infof("seed: %x", logger.Redacted{seed})
+type Redacted struct {
+	Value interface{}
+}
+
+// String - implements fmt.Stringer
+func (r Redacted) String() string {
+	return redactedText
+}
+
+// GoString - implements fmt.GoStringer
+func (r Redacted) GoString() string {
+	return redactedText
+}
+
+// Format - implements fmt.Formatter so that all verbs are redacted
+func (r Redacted) Format(f fmt.State, verb rune) {
+	_, _ = f.Write([]byte(redactedText))
+}
+
+// MarshalText - implements encoding.TextMarshaler
+func (r Redacted) MarshalText() ([]byte, error) {
+	return []byte(redactedText), nil
+}
+
+// RedactionRules - text to remove from log messages
+//
+// Patterns are regular expressions whose matches are replaced and
+// Fields are keys of key=value items whose values are replaced
+type RedactionRules struct {
+	Patterns []string `libucl:"patterns" hcl:"patterns" json:"patterns" yaml:"patterns,omitempty" toml:"patterns,omitempty"`
+	Fields   []string `libucl:"fields" hcl:"fields" json:"fields" yaml:"fields,omitempty" toml:"fields,omitempty"`
+}
+
+// RedactionConfiguration - rules for all tags plus extra rules for
+// specific tags, the tag keys are matched as for levels so "p2p" covers
+// "p2p" and all of its descendants while "p2p.*" covers only the
+// descendants; the rules of every matching key are applied
+type RedactionConfiguration struct {
+	Patterns []string                  `libucl:"patterns" hcl:"patterns" json:"patterns" yaml:"patterns,omitempty" toml:"patterns,omitempty"`
+	Fields   []string                  `libucl:"fields" hcl:"fields" json:"fields" yaml:"fields,omitempty" toml:"fields,omitempty"`
+	Tags     map[string]RedactionRules `libucl:"tags" hcl:"tags" json:"tags" yaml:"tags,omitempty" toml:"tags,omitempty"`
+}
+
+// a compiled rule
+type rule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// compiled rules
+type redactor struct {
+	global  []rule
+	tags    map[string][]rule
+	byTag   sync.Map // tag → []rule, all rules for a tag
+	enabled bool
+}
+
+// the current rules, nil if there are none
+var currentRedactor atomic.Value
+
+// convert rules to regular expressions, field is the name of the
+// configuration setting for errors
+func compileRules(field string, patterns []string, fields []string) ([]rule, error) {
+	result := make([]rule, 0, len(patterns)+1)
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if nil != err {
+			return nil, &FieldError{Field: field + ".Patterns[" + strconv.Itoa(i) + "]", Value: p, Reason: "is not a regular expression"}
+		}
+		result = append(result, rule{re: re, replacement: redactedText})
+	}
+
+	if 0 != len(fields) {
+		keys := make([]string, len(fields))
+		for i, f := range fields {
+			keys[i] = regexp.QuoteMeta(f)
+		}
+		// key=value or key="quoted value"
+		re := regexp.MustCompile(`(^|\s)(` + strings.Join(keys, "|") + `)=(?:"(?:[^"\\]|\\.)*"|\S*)`)
+		result = append(result, rule{re: re, replacement: "${1}${2}=" + redactedText})
+	}
+	return result, nil
+}
+
+// set up the redaction rules from the configuration
+func setRedaction(configuration RedactionConfiguration) error {
+	r := &redactor{
+		tags: make(map[string][]rule),
+	}
+
+	var err error
+	r.global, err = compileRules("Redact", configuration.Patterns, configuration.Fields)
+	if nil != err {
+		return err
+	}
+	r.enabled = 0 != len(r.global)
+
+	for tag, rules := range configuration.Tags {
+		r.tags[tag], err = compileRules("Redact.Tags["+tag+"]", rules.Patterns, rules.Fields)
+		if nil != err {
+			return err
+		}
+		r.enabled = r.enabled || 0 != len(r.tags[tag])
+	}
+
+	currentRedactor.Store(r)
+	return nil
+}
+
+// check the redaction rules, reporting every invalid pattern
+func validateRedaction(configuration RedactionConfiguration) error {
+	errs := ValidationErrors{}
+	_, err := compileRules("Redact", configuration.Patterns, configuration.Fields)
+	errs.add(err)
+	for _, tag := range sortedKeys(configuration.Tags) {
+		rules := configuration.Tags[tag]
+		_, err := compileRules("Redact.Tags["+tag+"]", rules.Patterns, rules.Fields)
+		errs.add(err)
+	}
+	return errs.err()
+}
+
+// remove all redaction rules
+func clearRedaction() {
+	currentRedactor.Store(&redactor{})
+}
+
+// all the rules applicable to a tag
+func (r *redactor) rules(tag string) []rule {
+	if rules, ok := r.byTag.Load(tag); ok {
+		return rules.([]rule)
+	}
+
+	// the rules of every key that applies, as for levels a key
+	// covers the tag and all of its descendants
+	rules := r.global
+	for _, key := range tagKeys(tag) {
+		if extra, ok := r.tags[key]; ok {
+			rules = append(rules[:len(rules):len(rules)], extra...)
+		}
+	}
+	r.byTag.Store(tag, rules)
+	return rules
+}
+
+// apply redaction to the text of a "<tag>: <text>" message
+func redact(message string) string {
+	r, _ := currentRedactor.Load().(*redactor)
+	if nil == r || !r.enabled {
+		return message
+	}
+
+	tag := ""
+	prefix := ""
+	text := message
+	if n := strings.Index(message, tagSuffix); n >= 0 {
+		tag = message[:n]
+		prefix = message[:n+len(tagSuffix)]
+		text = message[n+len(tagSuffix):]
+	}
+
+	rules := r.rules(tag)
+	if 0 == len(rules) {
+		return message
+	}
+	for _, rl := range rules {
+		text = rl.re.ReplaceAllString(text, rl.replacement)
+	}
+	return prefix + text
+}
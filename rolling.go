@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"sync"
+
+	"github.com/cihub/seelog"
+)
+
+// name of the custom receiver that writes the log file
+const fileReceiver = "logfile"
+
+// writes the log file and rotates it by size in the same way as the
+// seelog rolling file writer: the full file is renamed to "<file>.<n>",
+// n being one more than the newest archive, and the oldest archives
+// beyond the count are deleted
+//
+// new files get their mode and ownership before anything is written to
+// them, and each closed segment is passed to the rotated functions
+// e.g. to sign it
+type rollingFile struct {
+	sync.Mutex
+	filepath    string
+	size        int64 // rotate once the file reaches this size
+	count       int   // number of archives kept
+	permissions filePermissions
+	rotated     []func(segment string) error
+	file        *os.File
+	written     int64
+}
+
+func (r *rollingFile) ReceiveMessage(message string, l seelog.LogLevel, context seelog.LogContextInterface) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if nil == r.file {
+		if err := r.open(); nil != err {
+			return err
+		}
+	}
+	if r.written >= r.size {
+		if err := r.rotate(); nil != err {
+			return err
+		}
+		if err := r.open(); nil != err {
+			return err
+		}
+	}
+
+	n, err := r.file.WriteString(message)
+	r.written += int64(n)
+	return err
+}
+
+// open the current file, creating it with its permissions if necessary
+// must be called with the rollingFile locked
+func (r *rollingFile) open() error {
+	mode := os.FileMode(0o666)
+	if 0 != r.permissions.mode {
+		mode = r.permissions.mode
+	}
+
+	created := true
+	f, err := os.OpenFile(r.filepath, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, mode)
+	if os.IsExist(err) {
+		created = false
+		f, err = os.OpenFile(r.filepath, os.O_WRONLY|os.O_APPEND, 0)
+	}
+	if nil != err {
+		return err
+	}
+
+	// the umask may have cleared some bits of the mode
+	if created {
+		if err := r.permissions.apply(r.filepath); nil != err {
+			f.Close()
+			return err
+		}
+	}
+
+	info, err := f.Stat()
+	if nil != err {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.written = info.Size()
+	return nil
+}
+
+// archive the current file and delete the oldest archives
+// must be called with the rollingFile locked
+func (r *rollingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if nil != err {
+		return err
+	}
+
+	directory, file := path.Split(r.filepath)
+	files, err := RotatedFiles(directory, file)
+	if nil != err {
+		return err
+	}
+	archives := files
+	if n := len(files); 0 != n && r.filepath == files[n-1] {
+		archives = files[:n-1]
+	}
+
+	next := 1
+	if n := len(archives); 0 != n {
+		last, _ := rotatedNumber(file, segmentName(path.Base(archives[n-1])))
+		next = last + 1
+	}
+	segment := r.filepath + "." + strconv.Itoa(next)
+	err = os.Rename(r.filepath, segment)
+	if nil != err {
+		return err
+	}
+
+	archives = append(archives, segment)
+	for len(archives) > r.count {
+		os.Remove(archives[0])
+		archives = archives[1:]
+	}
+
+	for _, f := range r.rotated {
+		if err := f(segment); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *rollingFile) AfterParse(seelog.CustomReceiverInitArgs) error {
+	return nil
+}
+
+func (r *rollingFile) Flush() {
+}
+
+func (r *rollingFile) Close() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if nil == r.file {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+// sampling types
+const (
+	SampleFirst  = "first"  // first N per interval then every Mth
+	SampleBucket = "bucket" // token bucket
+	SampleRandom = "random" // pass with a fixed probability
+)
+
+// default period for reporting suppressed records
+const defaultSamplingSummary = time.Minute
+
+// Sampling - policy to limit the number of records from a tag
+//
+// example of ucl/hcl configuration
+//   sampling {
+//     peer {
+//       type = "first"
+//       first = 10
+//       thereafter = 100
+//       interval = "1s"
+//       bypass_errors = true
+//     }
+//     "rpc.*" {
+//       type = "bucket"
+//       rate = 5.0
+//       burst = 20
+//     }
+//   }
+type Sampling struct {
+	Type         string  `libucl:"type" hcl:"type" json:"type" yaml:"type,omitempty" toml:"type,omitempty"`
+	First        int     `libucl:"first" hcl:"first" json:"first" yaml:"first,omitempty" toml:"first,omitempty,omitzero"`
+	Thereafter   int     `libucl:"thereafter" hcl:"thereafter" json:"thereafter" yaml:"thereafter,omitempty" toml:"thereafter,omitempty,omitzero"`
+	Interval     string  `libucl:"interval" hcl:"interval" json:"interval" yaml:"interval,omitempty" toml:"interval,omitempty"`
+	Rate         float64 `libucl:"rate" hcl:"rate" json:"rate" yaml:"rate,omitempty" toml:"rate,omitempty,omitzero"`
+	Burst        int     `libucl:"burst" hcl:"burst" json:"burst" yaml:"burst,omitempty" toml:"burst,omitempty,omitzero"`
+	Probability  float64 `libucl:"probability" hcl:"probability" json:"probability" yaml:"probability,omitempty" toml:"probability,omitempty,omitzero"`
+	BypassErrors bool    `libucl:"bypass_errors" hcl:"bypass_errors" json:"bypass_errors" yaml:"bypass_errors,omitempty" toml:"bypass_errors,omitempty"`
+}
+
+// the sampling state of a single tag
+type sampler struct {
+	sync.Mutex
+	textPrefix  string
+	policy      Sampling
+	interval    time.Duration
+	windowStart time.Time
+	count       int
+	tokens      float64
+	last        time.Time
+	suppressed  uint64
+}
+
+// check a sampling policy
+func (policy Sampling) validate(tag string) (time.Duration, error) {
+	field := "Sampling[" + tag + "]."
+	switch policy.Type {
+	case SampleFirst:
+		interval, err := time.ParseDuration(policy.Interval)
+		if nil != err || interval <= 0 {
+			return 0, &FieldError{Field: field + "Interval", Value: policy.Interval, Reason: "is not a positive duration"}
+		}
+		if policy.First < 0 {
+			return 0, &FieldError{Field: field + "First", Value: strconv.Itoa(policy.First), Reason: "cannot be negative"}
+		}
+		if policy.Thereafter < 0 {
+			return 0, &FieldError{Field: field + "Thereafter", Value: strconv.Itoa(policy.Thereafter), Reason: "cannot be negative"}
+		}
+		return interval, nil
+	case SampleBucket:
+		if policy.Rate <= 0 {
+			return 0, &FieldError{Field: field + "Rate", Value: strconv.FormatFloat(policy.Rate, 'g', -1, 64), Reason: "must be positive"}
+		}
+		if policy.Burst < 1 {
+			return 0, &FieldError{Field: field + "Burst", Value: strconv.Itoa(policy.Burst), Reason: "must be positive"}
+		}
+	case SampleRandom:
+		if policy.Probability < 0 || policy.Probability > 1 {
+			return 0, &FieldError{Field: field + "Probability", Value: strconv.FormatFloat(policy.Probability, 'g', -1, 64), Reason: "must be from 0 to 1"}
+		}
+	default:
+		return 0, &FieldError{Field: field + "Type", Value: policy.Type, Reason: "is not a sampling type"}
+	}
+	return 0, nil
+}
+
+// create the sampler for a tag, the policy must be valid
+func newSampler(tag string, policy Sampling) *sampler {
+	interval, _ := policy.validate(tag)
+	return &sampler{
+		textPrefix: tag + tagSuffix,
+		policy:     policy,
+		interval:   interval,
+		tokens:     float64(policy.Burst),
+		last:       time.Now(),
+	}
+}
+
+func (s *sampler) allow(levelNumber int, message string) bool {
+	if s.policy.BypassErrors && levelNumber >= level.ErrorLevel {
+		return true
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	ok := false
+	now := time.Now()
+	switch s.policy.Type {
+	case SampleFirst:
+		if now.Sub(s.windowStart) >= s.interval {
+			s.windowStart = now
+			s.count = 0
+		}
+		s.count += 1
+		if s.count <= s.policy.First {
+			ok = true
+		} else if s.policy.Thereafter > 0 {
+			ok = 0 == (s.count-s.policy.First)%s.policy.Thereafter
+		}
+
+	case SampleBucket:
+		s.tokens += now.Sub(s.last).Seconds() * s.policy.Rate
+		if s.tokens > float64(s.policy.Burst) {
+			s.tokens = float64(s.policy.Burst)
+		}
+		s.last = now
+		if s.tokens >= 1 {
+			s.tokens -= 1
+			ok = true
+		}
+
+	case SampleRandom:
+		ok = rand.Float64() < s.policy.Probability
+	}
+
+	if !ok {
+		s.suppressed += 1
+	}
+	return ok
+}
+
+// return and reset the number of suppressed records
+func (s *sampler) takeSuppressed() uint64 {
+	s.Lock()
+	defer s.Unlock()
+
+	n := s.suppressed
+	s.suppressed = 0
+	return n
+}
+
+// log the number of suppressed records for each tag
+// globalData must be locked by caller
+func summariseSampling() {
+	tags := make([]string, 0, len(globalData.samplers))
+	for tag := range globalData.samplers {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		s := globalData.samplers[tag]
+		if n := s.takeSuppressed(); n > 0 {
+			_ = seelog.Current.Warn(s.textPrefix + fmt.Sprintf("sampling suppressed %d records", n))
+		}
+	}
+}
+
+// check the sampling configuration and determine the summary interval
+func validateSampling(policies map[string]Sampling, summary string) (time.Duration, error) {
+	interval := defaultSamplingSummary
+	if "" != summary {
+		var err error
+		interval, err = time.ParseDuration(summary)
+		if nil != err || interval <= 0 {
+			return 0, &FieldError{Field: "SamplingSummary", Value: summary, Reason: "is not a positive duration"}
+		}
+	}
+	errs := ValidationErrors{}
+	for _, tag := range sortedKeys(policies) {
+		_, err := policies[tag].validate(tag)
+		errs.add(err)
+	}
+	return interval, errs.err()
+}
+
+// set up sampling from a validated configuration
+// globalData must be locked by caller
+func startSampling(policies map[string]Sampling, interval time.Duration) {
+	globalData.sampling = policies
+	globalData.samplers = make(map[string]*sampler)
+	if 0 == len(policies) {
+		return
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	globalData.stopSampling = func() {
+		close(stop)
+		<-done
+	}
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				globalData.Lock()
+				summariseSampling()
+				globalData.Unlock()
+			}
+		}
+	}()
+}
+
+// stop the summary and report any remaining suppressed records
+func stopSampling() {
+	if nil != globalData.stopSampling {
+		globalData.stopSampling()
+		globalData.stopSampling = nil
+	}
+
+	globalData.Lock()
+	summariseSampling()
+	globalData.sampling = nil
+	globalData.samplers = nil
+	globalData.Unlock()
+}
+
+// the sampler for a tag, if any
+// globalData must be locked by caller
+func samplerForTag(tag string) *sampler {
+	if s, ok := globalData.samplers[tag]; ok {
+		return s
+	}
+	policy, ok := lookupTag(globalData.sampling, tag)
+	if !ok {
+		return nil
+	}
+	s := newSampler(tag, policy)
+	globalData.samplers[tag] = s
+	return s
+}
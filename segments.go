@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// when a signer is configured each log file segment gets a detached
+// signature "<segment>.sig" containing the hex Ed25519 signature of
+// the segment contents; archives are signed as they are rotated and
+// the current file is signed by Finalise
+const signatureSuffix = ".sig"
+
+// check that a signer produces Ed25519 signatures
+func validSigner(signer crypto.Signer) error {
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return &FieldError{Field: "Signer", Value: fmt.Sprintf("%T", signer.Public()), Reason: "must be an Ed25519 key"}
+	}
+	return nil
+}
+
+// sign each segment as it is rotated and remove the signatures of
+// archives that rotation has deleted
+func segmentSigner(signer crypto.Signer, filepath string) func(segment string) error {
+	return func(segment string) error {
+		err := signSegment(signer, segment)
+		if nil != err {
+			return err
+		}
+		dir, file := path.Split(filepath)
+		files, err := RotatedFiles(dir, file)
+		if nil != err {
+			return err
+		}
+		return removeOrphanSignatures(dir, file, files)
+	}
+}
+
+// write the detached signature for a segment
+func signSegment(signer crypto.Signer, segment string) error {
+	data, err := os.ReadFile(segment)
+	if nil != err {
+		return err
+	}
+	signature, err := signer.Sign(nil, data, crypto.Hash(0))
+	if nil != err {
+		return err
+	}
+	return os.WriteFile(segment+signatureSuffix, []byte(hex.EncodeToString(signature)+"\n"), 0600)
+}
+
+// remove signatures of archives that rotation has deleted
+func removeOrphanSignatures(directory string, file string, files []string) error {
+	segments := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		segments[segmentName(path.Base(f))] = struct{}{}
+	}
+
+	entries, err := os.ReadDir(directory)
+	if nil != err {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, signatureSuffix) {
+			continue
+		}
+		segment := strings.TrimSuffix(name, signatureSuffix)
+		if _, ok := segments[segment]; ok {
+			continue
+		}
+		if _, ok := rotatedNumber(file, segment); ok {
+			os.Remove(path.Join(directory, name))
+		}
+	}
+	return nil
+}
+
+// SignatureError - a log segment whose signature is missing or invalid
+type SignatureError struct {
+	File   string
+	Reason string
+}
+
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("%s: %s", e.File, e.Reason)
+}
+
+// VerifySignatures - check the detached signatures of a log file and
+// its archives against a public key
+//
+// this is intended for closed logs, i.e. after Finalise has signed the
+// current file; returns a *SignatureError for the first failure
+func VerifySignatures(directory string, file string, key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+
+	files, err := RotatedFiles(directory, file)
+	if nil != err {
+		return err
+	}
+	if 0 == len(files) {
+		return &SignatureError{File: path.Join(directory, file), Reason: "no log files"}
+	}
+
+	for _, name := range files {
+		// a compressed archive keeps the signature of its original
+		segment := segmentName(name)
+		s, err := os.ReadFile(segment + signatureSuffix)
+		if os.IsNotExist(err) {
+			return &SignatureError{File: name, Reason: "signature missing"}
+		} else if nil != err {
+			return err
+		}
+		signature, err := hex.DecodeString(string(bytes.TrimSpace(s)))
+		if nil != err || len(signature) != ed25519.SignatureSize {
+			return &SignatureError{File: name, Reason: "signature invalid"}
+		}
+
+		f, err := openSegment(name)
+		if nil != err {
+			return err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if nil != err {
+			return err
+		}
+		if !ed25519.Verify(key, data, signature) {
+			return &SignatureError{File: name, Reason: "signature does not match"}
+		}
+	}
+
+	return nil
+}
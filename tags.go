@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"strings"
+)
+
+// hierarchical tags are dot separated e.g. "p2p.peer.handshake"
+//
+// a level key of "p2p" applies to the tag "p2p" and all of its
+// descendants, "p2p.*" applies only to the descendants; when several
+// keys apply the longest match wins and "DEFAULT" is the last resort
+const (
+	tagSeparator = "."
+	tagWildcard  = tagSeparator + "*"
+)
+
+// determine the level for a tag from the levelMap
+func levelForTag(tag string) string {
+	if l, ok := lookupTag(levelMap, tag); ok {
+		return l
+	}
+	if l, ok := levelMap[DefaultTag]; ok {
+		return l
+	}
+	return DefaultLevel
+}
+
+// find the most specific setting for a tag in a map keyed by tags
+// and tag patterns
+func lookupTag[T any](m map[string]T, tag string) (T, bool) {
+	for _, key := range tagKeys(tag) {
+		if v, ok := m[key]; ok {
+			return v, true
+		}
+	}
+
+	var none T
+	return none, false
+}
+
+// the keys that apply to a tag, most specific first
+// e.g. "p2p.peer" gives: "p2p.peer", "p2p.*", "p2p"
+func tagKeys(tag string) []string {
+	keys := []string{tag}
+	prefix := tag
+	for {
+		n := strings.LastIndex(prefix, tagSeparator)
+		if n < 0 {
+			return keys
+		}
+		prefix = prefix[:n]
+		keys = append(keys, prefix+tagWildcard, prefix)
+	}
+}
+
+// check if a tag is selected by a pattern
+// a pattern is either a plain tag, which must match exactly, or
+// "prefix.*" which matches all descendants of prefix
+func tagMatches(pattern, tag string) bool {
+	if !strings.HasSuffix(pattern, tagWildcard) {
+		return pattern == tag
+	}
+	prefix := pattern[:len(pattern)-len("*")]
+	return len(tag) > len(prefix) && strings.HasPrefix(tag, prefix)
+}
@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitmark-inc/logger/level"
+)
+
+// FieldError - a problem with one configuration setting
+type FieldError struct {
+	Field  string // name of the setting e.g. "Size" or "Levels[main]"
+	Value  string // the configured value
+	Reason string // what is wrong with the value
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + strconv.Quote(e.Value) + " " + e.Reason
+}
+
+// ValidationErrors - all the problems found in a configuration
+type ValidationErrors []*FieldError
+
+func (v ValidationErrors) Error() string {
+	s := make([]string, len(v))
+	for i, e := range v {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// add an error, either a *FieldError or ValidationErrors
+func (v *ValidationErrors) add(err error) {
+	switch e := err.(type) {
+	case nil:
+	case *FieldError:
+		*v = append(*v, e)
+	case ValidationErrors:
+		*v = append(*v, e...)
+	default:
+		*v = append(*v, &FieldError{Reason: err.Error()})
+	}
+}
+
+// nil if there are no errors, so the result can be returned as error
+func (v ValidationErrors) err() error {
+	if 0 == len(v) {
+		return nil
+	}
+	return v
+}
+
+// Validate - check a configuration without initialising the logger
+//
+// returns nil or ValidationErrors listing every problem found,
+// including unknown level names in Levels, which Initialise ignores
+// unless Strict is set
+func (configuration Configuration) Validate() error {
+	return configuration.validate(true).err()
+}
+
+// check all settings that do not depend on the file system
+func (configuration Configuration) validate(levels bool) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if nil != configuration.Receiver {
+		// without a log file there is nothing to chain or sign
+		if configuration.Chain {
+			errs.add(&FieldError{Field: "Chain", Value: "true", Reason: "requires a log file, not a Receiver"})
+		}
+		if nil != configuration.Signer {
+			errs.add(&FieldError{Field: "Signer", Value: fmt.Sprintf("%T", configuration.Signer), Reason: "requires a log file, not a Receiver"})
+		}
+	} else {
+		errs.add(configuration.validateFile())
+	}
+
+	if levels {
+		for _, tag := range sortedKeys(configuration.Levels) {
+			name := configuration.Levels[tag]
+			if _, ok := level.Number(name); !ok {
+				errs.add(&FieldError{Field: "Levels[" + tag + "]", Value: name, Reason: "is not a level"})
+			}
+		}
+	}
+
+	errs.add(validateConsoleStyle(configuration.ConsoleStyle))
+
+	errs.add(validateTimestamp(configuration.Timestamp))
+
+	_, _, err := validatePermissions(configuration)
+	errs.add(err)
+
+	errs.add(validateRedaction(configuration.Redact))
+
+	_, err = validateSampling(configuration.Sampling, configuration.SamplingSummary)
+	errs.add(err)
+
+	_, err = validateDeduplicate(configuration.Deduplicate)
+	errs.add(err)
+
+	_, err = validateOutputLevels(configuration.OutputLevels, configuration.Console)
+	errs.add(err)
+
+	if nil != configuration.Signer {
+		errs.add(validSigner(configuration.Signer))
+	}
+
+	return errs
+}
+
+// check the log file settings
+func (configuration Configuration) validateFile() error {
+	errs := ValidationErrors{}
+
+	if "" == configuration.Directory {
+		errs.add(&FieldError{Field: "Directory", Reason: "cannot be empty"})
+	}
+
+	if "" == configuration.File {
+		errs.add(&FieldError{Field: "File", Reason: "cannot be empty"})
+	} else if d, f := path.Split(configuration.File); "" != d && f != configuration.File {
+		errs.add(&FieldError{Field: "File", Value: configuration.File, Reason: "cannot be a path name"})
+	}
+
+	if configuration.Size < minimumSize {
+		errs.add(&FieldError{Field: "Size", Value: strconv.Itoa(configuration.Size), Reason: "cannot be less than: " + strconv.Itoa(minimumSize)})
+	}
+
+	if configuration.Count < minimumCount {
+		errs.add(&FieldError{Field: "Count", Value: strconv.Itoa(configuration.Count), Reason: "cannot be less than: " + strconv.Itoa(minimumCount)})
+	}
+
+	return errs.err()
+}
+
+// the keys of a map in order, so that errors are reported in a
+// consistent order
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: ISC
+// Copyright (c) 2014-2023 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// verify the hash chain of a log file and its rotated archives
+//
+// usage:
+//
+//	verifylogchain -directory /var/lib/app/log -file app.log
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bitmark-inc/logger"
+)
+
+func main() {
+	directory := flag.String("directory", ".", "directory containing the log files")
+	file := flag.String("file", "", "name of the current log file")
+	flag.Parse()
+
+	if "" == *file {
+		fmt.Fprintf(os.Stderr, "verifylogchain: -file is required\n")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := logger.VerifyChain(*directory, *file)
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "verifylogchain: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s: chain verified\n", *file)
+}